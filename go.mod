@@ -1,11 +1,3 @@
 module github.com/yxx1912008/linker-ffmpeg-go
 
 go 1.22.5
-
-require github.com/u2takey/ffmpeg-go v0.5.0
-
-require (
-	github.com/aws/aws-sdk-go v1.55.8 // indirect
-	github.com/jmespath/go-jmespath v0.4.0 // indirect
-	github.com/u2takey/go-utils v0.3.1 // indirect
-)
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"compress/gzip"
 	"io"
+	"io/fs"
+	"path"
+	"strings"
 	"sync"
 )
 
@@ -13,6 +16,13 @@ var ffmpegCompressedBinary []byte
 // ffprobeCompressedBinary 存储当前平台的FFprobe压缩二进制数据
 var ffprobeCompressedBinary []byte
 
+// ffmpegDigest和ffprobeDigest存储与压缩数据一同嵌入的.sha256文件内容，由scripts/package-binaries.sh生成
+// 摘要针对解压后的二进制文件，格式与sha256sum输出兼容
+var (
+	ffmpegDigest  string
+	ffprobeDigest string
+)
+
 // 缓存解压后的二进制数据，避免重复解压
 var (
 	ffmpegDecompressedBinary  []byte
@@ -21,6 +31,20 @@ var (
 	ffprobeOnce               sync.Once
 )
 
+// loadEmbeddedBinaries 从平台目录中读取压缩二进制及摘要文件
+// 文件名为"ffmpeg<ext>.gz"、"ffprobe<ext>.gz"及对应的".sha256"文件，ext在windows上为".exe"；
+// 目录中缺少的文件视为未嵌入
+func loadEmbeddedBinaries(bin fs.FS, dir, ext string) {
+	read := func(name string) []byte {
+		data, _ := fs.ReadFile(bin, path.Join(dir, name))
+		return data
+	}
+	ffmpegCompressedBinary = read("ffmpeg" + ext + ".gz")
+	ffprobeCompressedBinary = read("ffprobe" + ext + ".gz")
+	ffmpegDigest = string(read("ffmpeg" + ext + ".gz.sha256"))
+	ffprobeDigest = string(read("ffprobe" + ext + ".gz.sha256"))
+}
+
 // decompressGzip 解压gzip压缩的数据
 func decompressGzip(compressed []byte) ([]byte, error) {
 	if len(compressed) == 0 {
@@ -59,3 +83,34 @@ func GetFFprobeBinary() []byte {
 	})
 	return ffprobeDecompressedBinary
 }
+
+// HasFFmpegBinary 判断当前平台是否嵌入了FFmpeg二进制数据
+func HasFFmpegBinary() bool {
+	return len(ffmpegCompressedBinary) > 0
+}
+
+// HasFFprobeBinary 判断当前平台是否嵌入了FFprobe二进制数据
+func HasFFprobeBinary() bool {
+	return len(ffprobeCompressedBinary) > 0
+}
+
+// FFmpegSHA256 获取当前平台FFmpeg二进制文件（解压后）的SHA-256摘要，十六进制小写
+// 没有嵌入摘要时返回空字符串
+func FFmpegSHA256() string {
+	return parseDigest(ffmpegDigest)
+}
+
+// FFprobeSHA256 获取当前平台FFprobe二进制文件（解压后）的SHA-256摘要，十六进制小写
+// 没有嵌入摘要时返回空字符串
+func FFprobeSHA256() string {
+	return parseDigest(ffprobeDigest)
+}
+
+// parseDigest 从sha256sum格式的内容（"<hex>  <filename>"）中取出摘要
+func parseDigest(content string) string {
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
@@ -2,15 +2,14 @@
 
 package ffmpeg
 
-import _ "embed"
+import "embed"
 
-//go:embed bin/ffmpeg_darwin_amd64.gz
-var ffmpegMacx64CompressedBinary []byte
-
-//go:embed bin/ffprobe_darwin_amd64.gz
-var ffprobeMacx64CompressedBinary []byte
+// darwinAmd64Bin 存放darwin/amd64平台的压缩二进制文件，文件说明见bin/darwin_amd64/README.md
+// 目录中缺少文件时不嵌入任何二进制数据
+//
+//go:embed bin/darwin_amd64
+var darwinAmd64Bin embed.FS
 
 func init() {
-	ffmpegCompressedBinary = ffmpegMacx64CompressedBinary
-	ffprobeCompressedBinary = ffprobeMacx64CompressedBinary
+	loadEmbeddedBinaries(darwinAmd64Bin, "bin/darwin_amd64", "")
 }
@@ -2,15 +2,14 @@
 
 package ffmpeg
 
-import _ "embed"
+import "embed"
 
-//go:embed bin/ffmpeg_darwin_arm64.gz
-var ffmpegMacarm64CompressedBinary []byte
-
-//go:embed bin/ffprobe_darwin_arm64.gz
-var ffprobeMacarm64CompressedBinary []byte
+// darwinArm64Bin 存放darwin/arm64平台的压缩二进制文件，文件说明见bin/darwin_arm64/README.md
+// 目录中缺少文件时不嵌入任何二进制数据
+//
+//go:embed bin/darwin_arm64
+var darwinArm64Bin embed.FS
 
 func init() {
-	ffmpegCompressedBinary = ffmpegMacarm64CompressedBinary
-	ffprobeCompressedBinary = ffprobeMacarm64CompressedBinary
+	loadEmbeddedBinaries(darwinArm64Bin, "bin/darwin_arm64", "")
 }
@@ -0,0 +1,15 @@
+//go:build linux && amd64
+
+package ffmpeg
+
+import "embed"
+
+// linuxAmd64Bin 存放linux/amd64平台的压缩二进制文件，文件说明见bin/linux_amd64/README.md
+// 目录中缺少文件时不嵌入任何二进制数据
+//
+//go:embed bin/linux_amd64
+var linuxAmd64Bin embed.FS
+
+func init() {
+	loadEmbeddedBinaries(linuxAmd64Bin, "bin/linux_amd64", "")
+}
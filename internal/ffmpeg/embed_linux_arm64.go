@@ -0,0 +1,15 @@
+//go:build linux && arm64
+
+package ffmpeg
+
+import "embed"
+
+// linuxArm64Bin 存放linux/arm64平台的压缩二进制文件，文件说明见bin/linux_arm64/README.md
+// 目录中缺少文件时不嵌入任何二进制数据
+//
+//go:embed bin/linux_arm64
+var linuxArm64Bin embed.FS
+
+func init() {
+	loadEmbeddedBinaries(linuxArm64Bin, "bin/linux_arm64", "")
+}
@@ -2,17 +2,14 @@
 
 package ffmpeg
 
-import (
-	_ "embed"
-)
+import "embed"
 
-//go:embed bin/ffmpeg_windows_amd64.exe.gz
-var ffmpegWindowsx64CompressedBinary []byte
-
-//go:embed bin/ffprobe_windows_amd64.exe.gz
-var ffprobeWindowsx64CompressedBinary []byte
+// windowsAmd64Bin 存放windows/amd64平台的压缩二进制文件，文件说明见bin/windows_amd64/README.md
+// 目录中缺少文件时不嵌入任何二进制数据
+//
+//go:embed bin/windows_amd64
+var windowsAmd64Bin embed.FS
 
 func init() {
-	ffmpegCompressedBinary = ffmpegWindowsx64CompressedBinary
-	ffprobeCompressedBinary = ffprobeWindowsx64CompressedBinary
+	loadEmbeddedBinaries(windowsAmd64Bin, "bin/windows_amd64", ".exe")
 }
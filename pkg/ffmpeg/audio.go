@@ -0,0 +1,194 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxAudioQuality ExtractAudioParams.Quality的最大值
+const maxAudioQuality = 10
+
+// sourceAudioCodec 将ffprobe输出的编解码器名称转换为AudioCodec，无法对应时返回空字符串
+func sourceAudioCodec(codecName string) AudioCodec {
+	switch {
+	case codecName == "aac":
+		return AudioCodecAAC
+	case codecName == "mp3":
+		return AudioCodecMP3
+	case codecName == "opus":
+		return AudioCodecOpus
+	case codecName == "flac":
+		return AudioCodecFLAC
+	case strings.HasPrefix(codecName, "pcm_"):
+		return AudioCodecPCM
+	}
+	return ""
+}
+
+// container 返回实际使用的容器格式
+func (p *ExtractAudioParams) container() Container {
+	if p.Container != "" {
+		return p.Container
+	}
+	return containerFromPath(p.OutputPath)
+}
+
+// codec 返回实际使用的音频编码
+func (p *ExtractAudioParams) codec() AudioCodec {
+	if p.Codec != "" {
+		return p.Codec
+	}
+	return containerSpecs[p.container()].audioCodecs[0]
+}
+
+// hasEncodeOptions 是否设置了需要重新编码的参数
+func (p *ExtractAudioParams) hasEncodeOptions() bool {
+	return p.Bitrate > 0 || p.SampleRate > 0 || p.Channels > 0 || p.Quality > 0
+}
+
+// needsProbe 是否需要先通过ffprobe获取输入文件的音轨信息
+func (p *ExtractAudioParams) needsProbe() bool {
+	return p.AudioTrack > 0 || p.Language != "" || p.CopyIfCompatible || p.codec() == AudioCodecCopy
+}
+
+// Validate 校验提取音频流参数
+// 返回值:
+//
+//	error: 参数无效时返回包装了ErrInvalidParams的错误
+func (p *ExtractAudioParams) Validate() error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidParams, fmt.Sprintf(format, args...))
+	}
+
+	if err := validateInput(p.InputPath, p.Input); err != nil {
+		return err
+	}
+	if err := validateOutput(p.OutputPath, p.Output, p.Container, containerSpecs[p.Container].seekable); err != nil {
+		return err
+	}
+
+	container := p.container()
+	spec, ok := containerSpecs[container]
+	if !ok {
+		return invalid("unsupported container %q", container)
+	}
+	if err := p.timeRange().validate(); err != nil {
+		return err
+	}
+
+	if p.Codec != "" && p.Codec != AudioCodecCopy {
+		if _, ok := audioEncoders[p.Codec]; !ok {
+			return invalid("unsupported audio codec %q", p.Codec)
+		}
+		if !containsCodec(spec.audioCodecs, p.Codec) {
+			return invalid("audio codec %q is not supported in container %q", p.Codec, container)
+		}
+	}
+	if p.Bitrate < 0 || p.SampleRate < 0 || p.Channels < 0 || p.Quality < 0 || p.AudioTrack < 0 {
+		return invalid("bitrate, sample rate, channels, quality and audio track must not be negative")
+	}
+	if p.Quality > maxAudioQuality {
+		return invalid("quality %d is out of range 1-%d", p.Quality, maxAudioQuality)
+	}
+	if p.Quality > 0 && p.Bitrate > 0 {
+		return invalid("quality and bitrate are mutually exclusive")
+	}
+	codec := p.codec()
+	if p.Quality > 0 && codec != AudioCodecMP3 && codec != AudioCodecAAC {
+		return invalid("quality is not supported for audio codec %q", codec)
+	}
+	if codec == AudioCodecCopy && p.hasEncodeOptions() {
+		return invalid("audio encoding options cannot be used with stream copy")
+	}
+	// 数据流只能读取一次，无法先获取音轨信息
+	if p.Input != nil && p.needsProbe() {
+		return invalid("track selection and stream copy require an input path")
+	}
+
+	return nil
+}
+
+// selectAudioStream 按音轨序号和语言选择音频流
+// track从1开始，设置了language时在该语言的音轨中计数；track为0时优先选择默认音轨
+func selectAudioStream(info *MediaInfo, track int, language string) (*StreamInfo, error) {
+	var streams []StreamInfo
+	for _, stream := range info.AudioStreams() {
+		if language == "" || strings.EqualFold(stream.Language, language) {
+			streams = append(streams, stream)
+		}
+	}
+
+	if len(streams) == 0 {
+		if language != "" {
+			return nil, fmt.Errorf("%w: no audio stream with language %q", ErrStreamNotFound, language)
+		}
+		return nil, fmt.Errorf("%w: no audio stream", ErrStreamNotFound)
+	}
+
+	if track > 0 {
+		if track > len(streams) {
+			return nil, fmt.Errorf("%w: audio track %d requested but only %d available", ErrStreamNotFound, track, len(streams))
+		}
+		return &streams[track-1], nil
+	}
+
+	for i := range streams {
+		if streams[i].Disposition.Default {
+			return &streams[i], nil
+		}
+	}
+	return &streams[0], nil
+}
+
+// buildExtractAudioArgs 校验参数并构建提取音频流的ffmpeg命令行参数
+// stream为选中的源音频流，为nil时由ffmpeg自动选择音轨且不做直接复制的兼容性判断
+func buildExtractAudioArgs(p *ExtractAudioParams, stream *StreamInfo) ([]string, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	container := p.container()
+	spec := containerSpecs[container]
+	args := append([]string{"-y"}, p.timeRange().wrapInput(inputURL(p.InputPath, p.Input))...)
+	if stream != nil {
+		args = append(args, "-map", fmt.Sprintf("0:%d", stream.Index))
+	}
+	args = append(args, "-vn", "-sn", "-dn")
+
+	// 判断是否可以直接复制
+	codec := p.codec()
+	streamCopy := codec == AudioCodecCopy
+	if stream != nil && (streamCopy || p.CopyIfCompatible) {
+		source := sourceAudioCodec(stream.CodecName)
+		compatible := source != "" && containsCodec(spec.audioCodecs, source)
+		if streamCopy && !compatible {
+			return nil, fmt.Errorf("%w: source audio codec %q cannot be copied into container %q", ErrInvalidParams, stream.CodecName, container)
+		}
+		if compatible && (p.Codec == "" || p.Codec == source) && !p.hasEncodeOptions() {
+			streamCopy = true
+		}
+	}
+
+	if streamCopy {
+		args = append(args, "-c:a", "copy")
+	} else {
+		args = appendAudioEncodeArgs(args, codec, p.Bitrate, p.SampleRate, p.Channels)
+		if p.Quality > 0 {
+			switch codec {
+			case AudioCodecMP3:
+				// libmp3lame的-q:a范围为0（最高）到9（最低）
+				args = append(args, "-q:a", strconv.Itoa(maxAudioQuality-p.Quality))
+			case AudioCodecAAC:
+				// 内置aac编码器的-q:a范围约为0.1到2
+				args = append(args, "-q:a", strconv.FormatFloat(float64(p.Quality)*0.2, 'f', 1, 64))
+			}
+		}
+	}
+
+	if spec.faststart {
+		args = append(args, "-movflags", "+faststart")
+	}
+	args = append(args, "-f", spec.muxer, outputURL(p.OutputPath, p.Output))
+	return args, nil
+}
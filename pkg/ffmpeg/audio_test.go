@@ -0,0 +1,246 @@
+package ffmpeg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestBuildExtractAudioArgs 测试提取音频流参数到ffmpeg命令行参数的转换
+func TestBuildExtractAudioArgs(t *testing.T) {
+	aac := &StreamInfo{Index: 1, Type: StreamTypeAudio, CodecName: "aac"}
+	vorbis := &StreamInfo{Index: 2, Type: StreamTypeAudio, CodecName: "vorbis"}
+
+	tests := []struct {
+		name   string
+		params ExtractAudioParams
+		stream *StreamInfo
+		want   []string
+	}{
+		{
+			name:   "mp3 from extension",
+			params: ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.mp3"},
+			want:   []string{"-y", "-i", "in.mp4", "-vn", "-sn", "-dn", "-c:a", "libmp3lame", "-f", "mp3", "out.mp3"},
+		},
+		{
+			name:   "wav uses pcm",
+			params: ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.wav", SampleRate: 16000, Channels: 1},
+			want:   []string{"-y", "-i", "in.mp4", "-vn", "-sn", "-dn", "-c:a", "pcm_s16le", "-ar", "16000", "-ac", "1", "-f", "wav", "out.wav"},
+		},
+		{
+			name:   "m4a uses aac with bitrate",
+			params: ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.m4a", Bitrate: 192},
+			want:   []string{"-y", "-i", "in.mp4", "-vn", "-sn", "-dn", "-c:a", "aac", "-b:a", "192k", "-movflags", "+faststart", "-f", "ipod", "out.m4a"},
+		},
+		{
+			name:   "raw aac from extension",
+			params: ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.aac", Bitrate: 160},
+			want:   []string{"-y", "-i", "in.mp4", "-vn", "-sn", "-dn", "-c:a", "aac", "-b:a", "160k", "-f", "adts", "out.aac"},
+		},
+		{
+			name:   "raw aac copies aac source",
+			params: ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.aac", CopyIfCompatible: true},
+			stream: aac,
+			want:   []string{"-y", "-i", "in.mp4", "-map", "0:1", "-vn", "-sn", "-dn", "-c:a", "copy", "-f", "adts", "out.aac"},
+		},
+		{
+			name:   "mka from extension",
+			params: ExtractAudioParams{InputPath: "in.mkv", OutputPath: "out.mka"},
+			want:   []string{"-y", "-i", "in.mkv", "-vn", "-sn", "-dn", "-c:a", "libopus", "-f", "matroska", "out.mka"},
+		},
+		{
+			name:   "mka copies aac source",
+			params: ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.mka", CopyIfCompatible: true},
+			stream: aac,
+			want:   []string{"-y", "-i", "in.mp4", "-map", "0:1", "-vn", "-sn", "-dn", "-c:a", "copy", "-f", "matroska", "out.mka"},
+		},
+		{
+			name:   "oga from extension",
+			params: ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.oga"},
+			want:   []string{"-y", "-i", "in.mp4", "-vn", "-sn", "-dn", "-c:a", "libopus", "-f", "ogg", "out.oga"},
+		},
+		{
+			name:   "mp3 vbr quality",
+			params: ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.mp3", Quality: 8},
+			want:   []string{"-y", "-i", "in.mp4", "-vn", "-sn", "-dn", "-c:a", "libmp3lame", "-q:a", "2", "-f", "mp3", "out.mp3"},
+		},
+		{
+			name:   "copy when compatible",
+			params: ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.m4a", CopyIfCompatible: true},
+			stream: aac,
+			want:   []string{"-y", "-i", "in.mp4", "-map", "0:1", "-vn", "-sn", "-dn", "-c:a", "copy", "-movflags", "+faststart", "-f", "ipod", "out.m4a"},
+		},
+		{
+			name:   "encode when incompatible",
+			params: ExtractAudioParams{InputPath: "in.mkv", OutputPath: "out.m4a", CopyIfCompatible: true},
+			stream: vorbis,
+			want:   []string{"-y", "-i", "in.mkv", "-map", "0:2", "-vn", "-sn", "-dn", "-c:a", "aac", "-movflags", "+faststart", "-f", "ipod", "out.m4a"},
+		},
+		{
+			name:   "encode when options are set",
+			params: ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.m4a", CopyIfCompatible: true, Channels: 1},
+			stream: aac,
+			want:   []string{"-y", "-i", "in.mp4", "-map", "0:1", "-vn", "-sn", "-dn", "-c:a", "aac", "-ac", "1", "-movflags", "+faststart", "-f", "ipod", "out.m4a"},
+		},
+		{
+			name:   "forced copy into matroska",
+			params: ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.mka", Container: ContainerMKV, Codec: AudioCodecCopy},
+			stream: aac,
+			want:   []string{"-y", "-i", "in.mp4", "-map", "0:1", "-vn", "-sn", "-dn", "-c:a", "copy", "-f", "matroska", "out.mka"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildExtractAudioArgs(&tt.params, tt.stream)
+			if err != nil {
+				t.Fatalf("buildExtractAudioArgs() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildExtractAudioArgs() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+
+	// 源编码与容器不兼容时不能强制直接复制
+	_, err := buildExtractAudioArgs(&ExtractAudioParams{InputPath: "in.mkv", OutputPath: "out.mp3", Codec: AudioCodecCopy}, vorbis)
+	if !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("Expected ErrInvalidParams for incompatible copy, got %v", err)
+	}
+}
+
+// TestExtractAudioParamsValidate 测试无效的提取音频流参数被拒绝
+func TestExtractAudioParamsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		params ExtractAudioParams
+	}{
+		{"missing output", ExtractAudioParams{InputPath: "in.mp4"}},
+		{"unknown extension", ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.xyz"}},
+		{"codec not allowed in container", ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.wav", Codec: AudioCodecMP3}},
+		{"raw aac only holds aac", ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.aac", Codec: AudioCodecOpus}},
+		{"quality out of range", ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.mp3", Quality: 11}},
+		{"quality and bitrate", ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.mp3", Quality: 5, Bitrate: 128}},
+		{"quality unsupported", ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.flac", Quality: 5}},
+		{"copy with options", ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.m4a", Codec: AudioCodecCopy, SampleRate: 44100}},
+		{"negative track", ExtractAudioParams{InputPath: "in.mp4", OutputPath: "out.mp3", AudioTrack: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(); !errors.Is(err, ErrInvalidParams) {
+				t.Errorf("Validate() error = %v, want ErrInvalidParams", err)
+			}
+		})
+	}
+}
+
+// TestSelectAudioStream 测试按音轨序号和语言选择音频流
+func TestSelectAudioStream(t *testing.T) {
+	info := &MediaInfo{Streams: []StreamInfo{
+		{Index: 0, Type: StreamTypeVideo},
+		{Index: 1, Type: StreamTypeAudio, Language: "chi"},
+		{Index: 2, Type: StreamTypeAudio, Language: "eng", Disposition: Disposition{Default: true}},
+		{Index: 3, Type: StreamTypeAudio, Language: "eng"},
+	}}
+
+	tests := []struct {
+		name     string
+		track    int
+		language string
+		want     int
+	}{
+		{"default disposition", 0, "", 2},
+		{"track number", 1, "", 1},
+		{"language", 0, "ENG", 2},
+		{"track within language", 2, "eng", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := selectAudioStream(info, tt.track, tt.language)
+			if err != nil {
+				t.Fatalf("selectAudioStream() error = %v", err)
+			}
+			if stream.Index != tt.want {
+				t.Errorf("selectAudioStream() index = %d, want %d", stream.Index, tt.want)
+			}
+		})
+	}
+
+	if _, err := selectAudioStream(info, 0, "fre"); !errors.Is(err, ErrStreamNotFound) {
+		t.Errorf("Expected ErrStreamNotFound for missing language, got %v", err)
+	}
+	if _, err := selectAudioStream(info, 4, ""); !errors.Is(err, ErrStreamNotFound) {
+		t.Errorf("Expected ErrStreamNotFound for missing track, got %v", err)
+	}
+}
+
+// TestExtractAudioLanguageCopy 测试按语言选择音轨并在兼容时直接复制
+func TestExtractAudioLanguageCopy(t *testing.T) {
+	argsFile := filepath.Join(t.TempDir(), "args")
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `echo "$@" > "`+argsFile+`"`)
+
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, writeFakeFFprobe(t), nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	err = ffmpeg.ExtractAudio(&ExtractAudioParams{
+		InputPath:        "input.mp4",
+		OutputPath:       filepath.Join(t.TempDir(), "output.m4a"),
+		Language:         "chi",
+		CopyIfCompatible: true,
+	})
+	if err != nil {
+		t.Fatalf("ExtractAudio failed: %v", err)
+	}
+
+	data, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("Failed to read recorded arguments: %v", err)
+	}
+	args := string(data)
+	if !strings.Contains(args, "-map 0:2") || !strings.Contains(args, "-c:a copy") {
+		t.Errorf("Expected chinese track to be copied, got args: %s", args)
+	}
+}
+
+// TestExtractAudioTrackCountsAudioStreams 测试AudioTrack只按音频流计数，而不是Probe返回的流序号
+func TestExtractAudioTrackCountsAudioStreams(t *testing.T) {
+	argsFile := filepath.Join(t.TempDir(), "args")
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `echo "$@" > "`+argsFile+`"`)
+
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, writeFakeFFprobe(t), nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	tests := []struct {
+		track int
+		want  string
+	}{
+		{1, "-map 0:1 "},
+		{2, "-map 0:2 "},
+	}
+	for _, tt := range tests {
+		err := ffmpeg.ExtractAudio(&ExtractAudioParams{
+			InputPath:  "input.mp4",
+			OutputPath: filepath.Join(t.TempDir(), "output.mp3"),
+			AudioTrack: tt.track,
+		})
+		if err != nil {
+			t.Fatalf("ExtractAudio(AudioTrack=%d) failed: %v", tt.track, err)
+		}
+		data, err := os.ReadFile(argsFile)
+		if err != nil {
+			t.Fatalf("Failed to read recorded arguments: %v", err)
+		}
+		if args := string(data); !strings.Contains(args, tt.want) {
+			t.Errorf("AudioTrack=%d: expected %q, got args: %s", tt.track, tt.want, args)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentInstances 测试不同路径的FFmpeg实例并发执行时互不干扰
+// 使用 go test -race 运行可以检测数据竞争
+func TestConcurrentInstances(t *testing.T) {
+	type instance struct {
+		name     string
+		ffmpeg   *FFmpeg
+		duration int64
+	}
+
+	var instances []instance
+	for i, name := range []string{"first", "second"} {
+		// 每个模拟ffmpeg把自己的名字写入输出文件，模拟ffprobe返回不同的时长
+		fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `for last; do :; done
+echo `+name+` > "$last"
+`)
+		fakeFFprobe := writeFakeBinary(t, "ffprobe", fmt.Sprintf(`echo '{"format": {"duration": "%d.000000"}}'`, i+1))
+
+		ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, fakeFFprobe, func(progress *Progress) {})
+		if err != nil {
+			t.Fatalf("Failed to create FFmpeg instance: %v", err)
+		}
+		instances = append(instances, instance{name: name, ffmpeg: ffmpeg, duration: int64(i+1) * 1000})
+	}
+
+	outputDir := t.TempDir()
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+
+	for i := 0; i < 50; i++ {
+		inst := instances[i%len(instances)]
+		outputPath := filepath.Join(outputDir, fmt.Sprintf("output_%d.mp3", i))
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := inst.ffmpeg.ExtractAudio(&ExtractAudioParams{InputPath: "input.mp4", OutputPath: outputPath}); err != nil {
+				errs <- err
+				return
+			}
+			data, err := os.ReadFile(outputPath)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if got := string(data); got != inst.name+"\n" {
+				errs <- fmt.Errorf("output %s was written by %q, expected %q", outputPath, got, inst.name)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			duration, err := inst.ffmpeg.GetVideoDuration("input.mp4")
+			if err != nil {
+				errs <- err
+				return
+			}
+			if duration != inst.duration {
+				errs <- fmt.Errorf("instance %s got duration %d, expected %d", inst.name, duration, inst.duration)
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
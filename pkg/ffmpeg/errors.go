@@ -0,0 +1,180 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoEmbeddedBinary 当前平台没有内置FFmpeg/FFprobe二进制文件
+// 此时应通过NewFFmpegWithPath指定已安装的二进制文件路径
+var ErrNoEmbeddedBinary = errors.New("no embedded ffmpeg binary for this platform")
+
+// ErrInvalidParams 参数校验失败，在启动ffmpeg之前返回
+var ErrInvalidParams = errors.New("invalid parameters")
+
+// ErrStreamNotFound 输入文件中没有符合条件的媒体流
+var ErrStreamNotFound = errors.New("stream not found")
+
+// ErrNoChapters 按章节分段时输入文件中没有章节信息
+var ErrNoChapters = errors.New("input has no chapters")
+
+// ErrSegmentTooLarge 按大小分段时多次缩短分段时长后仍有分段超过MaxSize
+// 直接复制流时分段只能从关键帧开始，关键帧间隔过大的输入可以改用SplitModeReencode
+var ErrSegmentTooLarge = errors.New("segment exceeds max size")
+
+// ErrSeekableOutput 输出容器需要在写入结束后回到文件开头更新信息，不能写入io.Writer
+// 返回该错误时同时包装了ErrInvalidParams
+var ErrSeekableOutput = errors.New("container requires seekable output")
+
+// stderrTailLines CommandError中保留的stderr末尾行数
+const stderrTailLines = 20
+
+// ErrorKind 定义ffmpeg命令失败原因的分类
+// 调用方可以据此决定重试还是直接失败
+type ErrorKind int
+
+const (
+	ErrorKindUnknown          ErrorKind = iota // 无法识别的失败原因
+	ErrorKindInputNotFound                     // 输入文件不存在
+	ErrorKindUnsupportedCodec                  // 编解码器不支持或与容器不兼容
+	ErrorKindPermissionDenied                  // 没有读写权限
+	ErrorKindDiskFull                          // 磁盘空间不足
+	ErrorKindInvalidFilter                     // 过滤器不存在或参数错误
+	ErrorKindKilled                            // 进程被信号终止
+	ErrorKindCanceled                          // ctx被取消或超时
+)
+
+// String 返回失败原因的可读名称
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindInputNotFound:
+		return "input not found"
+	case ErrorKindUnsupportedCodec:
+		return "unsupported codec"
+	case ErrorKindPermissionDenied:
+		return "permission denied"
+	case ErrorKindDiskFull:
+		return "disk full"
+	case ErrorKindInvalidFilter:
+		return "invalid filter"
+	case ErrorKindKilled:
+		return "killed by signal"
+	case ErrorKindCanceled:
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
+
+// CommandError 定义ffmpeg/ffprobe命令执行失败时返回的错误
+// 可以通过errors.As获取，并根据Kind判断失败原因
+// 字段:
+//
+//	Args: 完整的命令行参数，Args[0]为可执行文件路径
+//	ExitCode: 进程退出码，未能启动或被信号终止时为-1
+//	Stderr: stderr输出的最后若干行，不包括showinfo等过滤器输出的逐帧日志
+//	Kind: 失败原因分类
+//	Err: 底层错误，如*exec.ExitError或ctx.Err()
+//
+// 示例:
+//
+//	var cmdErr *ffmpeg.CommandError
+//	if errors.As(err, &cmdErr) && cmdErr.Kind == ffmpeg.ErrorKindDiskFull {
+//	    // 稍后重试
+//	}
+type CommandError struct {
+	Args     []string  // 完整的命令行参数
+	ExitCode int       // 进程退出码
+	Stderr   []string  // stderr输出的最后若干行
+	Kind     ErrorKind // 失败原因分类
+	Err      error     // 底层错误
+}
+
+// Error 返回包含失败原因和最后一行stderr的错误信息
+func (e *CommandError) Error() string {
+	name := "command"
+	if len(e.Args) > 0 {
+		name = filepath.Base(e.Args[0])
+	}
+
+	msg := fmt.Sprintf("%s failed (%s, exit code %d)", name, e.Kind, e.ExitCode)
+	// 退出码已包含在信息中，无需重复*exec.ExitError的内容
+	var exitErr *exec.ExitError
+	if e.Err != nil && !errors.As(e.Err, &exitErr) {
+		msg += ": " + e.Err.Error()
+	}
+	if len(e.Stderr) > 0 {
+		msg += ": " + e.Stderr[len(e.Stderr)-1]
+	}
+	return msg
+}
+
+// Unwrap 返回底层错误，使errors.Is可以识别context.Canceled等错误
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// stderrPatterns 按优先级排列的stderr关键字及对应的失败原因
+var stderrPatterns = []struct {
+	kind     ErrorKind
+	patterns []string
+}{
+	{ErrorKindDiskFull, []string{"no space left on device", "disk quota exceeded"}},
+	{ErrorKindPermissionDenied, []string{"permission denied", "operation not permitted"}},
+	{ErrorKindInputNotFound, []string{"no such file or directory", "does not exist", "server returned 404"}},
+	{ErrorKindUnsupportedCodec, []string{"unknown encoder", "unknown decoder", "decoder (codec", "encoder (codec",
+		"could not find tag for codec", "not currently supported in container", "unsupported codec"}},
+	{ErrorKindInvalidFilter, []string{"no such filter", "error initializing filter", "error parsing filterchain",
+		"error parsing a filter", "invalid filter", "error reinitializing filters"}},
+}
+
+// newCommandError 根据命令执行结果构造CommandError并分类失败原因
+func newCommandError(ctx context.Context, args []string, stderr []string, err error) *CommandError {
+	cmdErr := &CommandError{
+		Args:     args,
+		ExitCode: -1,
+		Stderr:   stderr,
+		Kind:     ErrorKindUnknown,
+		Err:      err,
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		cmdErr.ExitCode = exitErr.ExitCode()
+	}
+
+	// 被取消时以ctx.Err()作为底层错误
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		cmdErr.Kind = ErrorKindCanceled
+		cmdErr.Err = ctxErr
+		return cmdErr
+	}
+
+	// 进程正常启动但没有退出码，说明被信号终止（如OOM killer）
+	if exitErr != nil && exitErr.ExitCode() == -1 {
+		cmdErr.Kind = ErrorKindKilled
+		return cmdErr
+	}
+
+	cmdErr.Kind = classifyStderr(stderr)
+	return cmdErr
+}
+
+// classifyStderr 根据stderr内容判断失败原因
+func classifyStderr(stderr []string) ErrorKind {
+	for _, group := range stderrPatterns {
+		for _, line := range stderr {
+			lower := strings.ToLower(line)
+			for _, pattern := range group.patterns {
+				if strings.Contains(lower, pattern) {
+					return group.kind
+				}
+			}
+		}
+	}
+	return ErrorKindUnknown
+}
@@ -0,0 +1,111 @@
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestClassifyStderr 测试根据stderr内容分类失败原因
+func TestClassifyStderr(t *testing.T) {
+	tests := []struct {
+		stderr string
+		kind   ErrorKind
+	}{
+		{"input.mp4: No such file or directory", ErrorKindInputNotFound},
+		{"Unknown encoder 'libfdk_aac'", ErrorKindUnsupportedCodec},
+		{"Could not find tag for codec pcm_s16le in stream #0, codec not currently supported in container", ErrorKindUnsupportedCodec},
+		{"/root/out.mp3: Permission denied", ErrorKindPermissionDenied},
+		{"av_interleaved_write_frame(): No space left on device", ErrorKindDiskFull},
+		{"No such filter: 'foo'", ErrorKindInvalidFilter},
+		{"Error initializing filter 'scale' with args '-3:-3'", ErrorKindInvalidFilter},
+		{"Conversion failed!", ErrorKindUnknown},
+	}
+
+	for _, tt := range tests {
+		if kind := classifyStderr([]string{"ffmpeg version 6.0", tt.stderr}); kind != tt.kind {
+			t.Errorf("classifyStderr(%q) = %s, want %s", tt.stderr, kind, tt.kind)
+		}
+	}
+}
+
+// TestCommandError 测试命令失败时返回可通过errors.As获取的CommandError
+func TestCommandError(t *testing.T) {
+	// 输出超过stderrTailLines行，最后一行为文件不存在的错误
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", fmt.Sprintf(`i=0
+while [ $i -lt %d ]; do echo "line $i" >&2; i=$((i+1)); done
+echo "input.mp4: No such file or directory" >&2
+exit 1
+`, stderrTailLines*2))
+
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	err = ffmpeg.ExtractAudio(&ExtractAudioParams{
+		InputPath:  "input.mp4",
+		OutputPath: filepath.Join(t.TempDir(), "output.mp3"),
+	})
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("Expected *CommandError, got %T: %v", err, err)
+	}
+	if cmdErr.Kind != ErrorKindInputNotFound {
+		t.Fatalf("Expected kind %s, got %s", ErrorKindInputNotFound, cmdErr.Kind)
+	}
+	if cmdErr.ExitCode != 1 {
+		t.Fatalf("Expected exit code 1, got %d", cmdErr.ExitCode)
+	}
+	if len(cmdErr.Args) == 0 || cmdErr.Args[0] != fakeFFmpeg {
+		t.Fatalf("Expected argv to start with %s, got %v", fakeFFmpeg, cmdErr.Args)
+	}
+	if len(cmdErr.Stderr) != stderrTailLines {
+		t.Fatalf("Expected %d stderr lines, got %d", stderrTailLines, len(cmdErr.Stderr))
+	}
+	if !strings.Contains(err.Error(), "No such file or directory") {
+		t.Fatalf("Expected error message to contain last stderr line, got %q", err.Error())
+	}
+}
+
+// TestCommandErrorFrameInfo 测试过滤器输出的逐帧日志不会挤掉真正的错误信息
+func TestCommandErrorFrameInfo(t *testing.T) {
+	// 写入失败后showinfo仍输出大量逐帧日志
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", fmt.Sprintf(`echo "[image2 @ 0x1] Could not open file : /out/frame_000001.jpg" >&2
+echo "/out/frame_000001.jpg: Permission denied" >&2
+i=0
+while [ $i -lt %d ]; do
+	echo "[Parsed_showinfo_1 @ 0x2] n: $i pts: $i pts_time:$i s:640x360 type:P" >&2
+	echo "[Parsed_showinfo_1 @ 0x2]   side data - SEI unregistered data:" >&2
+	i=$((i+1))
+done
+exit 1
+`, stderrTailLines*2))
+
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	_, err = ffmpeg.ExtractKeyFrames(&ExtractKeyFramesParams{
+		InputPath:     "input.mp4",
+		OutputDir:     t.TempDir(),
+		FrameInterval: 1,
+	})
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("Expected *CommandError, got %T: %v", err, err)
+	}
+	if cmdErr.Kind != ErrorKindPermissionDenied {
+		t.Fatalf("Expected kind %s, got %s", ErrorKindPermissionDenied, cmdErr.Kind)
+	}
+	for _, line := range cmdErr.Stderr {
+		if strings.Contains(line, "Parsed_showinfo") {
+			t.Fatalf("Expected frame info lines to be left out of stderr, got %q", line)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// waitDelay 进程被终止后等待其输出管道关闭的最长时间
+// 防止子进程派生的孙进程持有管道导致Wait永久阻塞
+const waitDelay = 5 * time.Second
+
+// runOptions 描述一次外部命令调用
+type runOptions struct {
+	binary   string            // 可执行文件路径
+	args     []string          // 命令行参数
+	stdin    io.Reader         // 标准输入，为nil时为空
+	stdout   io.Writer         // 标准输出，为nil时丢弃
+	stderr   func(string) bool // 逐行接收stderr输出，用于解析showinfo等过滤器的日志；返回true的行不计入CommandError
+	progress bool              // 是否通过-progress管道解析进度并回调
+	partial  bool              // 只是整个操作的中间步骤，报告进度但不发送完成事件
+	total    time.Duration     // 处理范围的总时长，用于计算进度百分比
+}
+
+// stderrCollector 按行收集命令的stderr输出，保留最后若干行
+type stderrCollector struct {
+	logger  *slog.Logger
+	onLine  func(string) bool // 每收到一行时调用，可为nil；返回true表示该行已被解析，不保留在tail中
+	partial []byte            // 尚未遇到换行符的残余数据
+	tail    []string          // 最后stderrTailLines行
+}
+
+func (c *stderrCollector) Write(p []byte) (int, error) {
+	// ffmpeg的统计信息以\r结尾，同样视为行分隔符
+	c.partial = append(c.partial, p...)
+	for {
+		i := bytes.IndexAny(c.partial, "\r\n")
+		if i < 0 {
+			break
+		}
+		c.addLine(string(c.partial[:i]))
+		c.partial = c.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// addLine 记录一行非空输出，超出上限时丢弃最早的行
+// 被onLine解析的逐帧日志可能有成百上千行，不保留在tail中，避免挤掉真正的错误信息
+func (c *stderrCollector) addLine(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	c.logger.Debug("stderr", "line", line)
+	if c.onLine != nil && c.onLine(line) {
+		return
+	}
+	c.tail = append(c.tail, line)
+	if len(c.tail) > stderrTailLines {
+		c.tail = c.tail[len(c.tail)-stderrTailLines:]
+	}
+}
+
+// lines 返回收集到的最后若干行，包括末尾没有换行符的残余内容
+func (c *stderrCollector) lines() []string {
+	if len(c.partial) > 0 {
+		c.addLine(string(c.partial))
+		c.partial = nil
+	}
+	return c.tail
+}
+
+// run 执行外部命令并等待其结束
+// 失败时返回*CommandError；ctx被取消或超时时会终止整个进程组，返回的错误包装了ctx.Err()
+func (f *FFmpeg) run(ctx context.Context, opts *runOptions) error {
+	cmd := exec.CommandContext(ctx, opts.binary, opts.args...)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	cmd.WaitDelay = waitDelay
+
+	argv := append([]string{opts.binary}, opts.args...)
+	logger := f.logger().With("cmd", filepath.Base(opts.binary))
+	stderr := &stderrCollector{logger: logger, onLine: opts.stderr}
+	cmd.Stdin = opts.stdin
+	cmd.Stdout = opts.stdout
+	cmd.Stderr = stderr
+
+	// 通过独立管道接收-progress输出，避免从stderr中抓取进度
+	var progressReader, progressWriter *os.File
+	if opts.progress && f.Callback != nil {
+		var url string
+		var err error
+		progressReader, progressWriter, url, err = progressPipe(cmd)
+		if err != nil {
+			return err
+		}
+		if progressReader != nil {
+			defer progressReader.Close()
+			cmd.Args = append([]string{cmd.Args[0], "-progress", url, "-nostats"}, cmd.Args[1:]...)
+			argv = cmd.Args
+		}
+	}
+
+	logger.DebugContext(ctx, "running command", "args", strings.Join(argv, " "))
+	start := time.Now()
+	err := cmd.Start()
+	progressDone := make(chan struct{})
+	if progressWriter != nil {
+		// 子进程已继承写端，父进程需关闭自己的副本以便读端在子进程退出后收到EOF
+		progressWriter.Close()
+	}
+	if err == nil && progressReader != nil {
+		go func() {
+			defer close(progressDone)
+			f.readProgress(progressReader, opts.total)
+		}()
+	} else {
+		close(progressDone)
+	}
+	if err == nil {
+		err = cmd.Wait()
+	}
+	select {
+	case <-progressDone:
+	case <-time.After(waitDelay):
+		// 孙进程仍持有写端时强制关闭读端
+		progressReader.Close()
+		<-progressDone
+	}
+
+	// 刷新末尾没有换行符的残余输出
+	stderrLines := stderr.lines()
+
+	if err != nil {
+		cmdErr := newCommandError(ctx, argv, stderrLines, err)
+
+		// 主动取消属于预期行为，只记录警告
+		level := slog.LevelError
+		if cmdErr.Kind == ErrorKindCanceled {
+			level = slog.LevelWarn
+		}
+		logger.Log(ctx, level, "command failed",
+			"duration", time.Since(start),
+			"exit_code", cmdErr.ExitCode,
+			"kind", cmdErr.Kind.String(),
+			"stderr", strings.Join(cmdErr.Stderr, "\n"))
+		return cmdErr
+	}
+	logger.InfoContext(ctx, "command finished", "duration", time.Since(start), "exit_code", 0)
+
+	// 发送完成进度，中间步骤由调用方在整个操作结束后发送
+	if opts.progress && !opts.partial {
+		f.reportCompleted()
+	}
+
+	return nil
+}
+
+// reportCompleted 通过进度回调报告整个操作已完成
+func (f *FFmpeg) reportCompleted() {
+	if f.Callback != nil {
+		f.Callback(&Progress{
+			Percentage: 100,
+			Status:     "completed",
+		})
+	}
+}
+
+// ffprobeBinary 返回ffprobe可执行文件路径
+// 未设置FFprobePath时，优先使用FFmpegPath同目录下的ffprobe，否则从PATH中查找
+func (f *FFmpeg) ffprobeBinary() string {
+	if f.FFprobePath != "" {
+		return f.FFprobePath
+	}
+
+	ffprobeFileName := "ffprobe"
+	if runtime.GOOS == "windows" {
+		ffprobeFileName += ".exe"
+	}
+	if f.FFmpegPath != "" {
+		sibling := filepath.Join(filepath.Dir(f.FFmpegPath), ffprobeFileName)
+		if _, err := os.Stat(sibling); err == nil {
+			return sibling
+		}
+	}
+	return ffprobeFileName
+}
+
+// listOutputFiles 列出目录中符合前缀和后缀的文件名及其修改时间
+func listOutputFiles(dir, prefix, suffix string) (map[string]time.Time, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]time.Time)
+	for _, file := range files {
+		if file.IsDir() || !strings.HasPrefix(file.Name(), prefix) || !strings.HasSuffix(file.Name(), suffix) {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		names[file.Name()] = info.ModTime()
+	}
+	return names, nil
+}
+
+// removeNewOutputFile 返回取消后清理输出文件的函数
+// 只有运行前不存在的文件才会被删除，避免在ffmpeg打开输出之前取消时误删用户原有的文件
+func removeNewOutputFile(path string) func() {
+	if _, err := os.Stat(path); err == nil {
+		return func() {}
+	}
+	return func() { os.Remove(path) }
+}
+
+// removeNewOutputFiles 删除本次运行中新生成的文件，用于取消后清理不完整的输出
+// existing为运行前已存在的文件名及修改时间
+func removeNewOutputFiles(dir, prefix, suffix string, existing map[string]time.Time) {
+	current, err := listOutputFiles(dir, prefix, suffix)
+	if err != nil {
+		return
+	}
+	for name := range current {
+		if _, ok := existing[name]; !ok {
+			os.Remove(filepath.Join(dir, name))
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFakeBinary 在临时目录中生成一个模拟ffmpeg/ffprobe的shell脚本
+// 脚本会响应创建实例时校验用的-version参数
+func writeFakeBinary(t *testing.T, name, script string) string {
+	t.Helper()
+	return writeFakeScript(t, name, fmt.Sprintf("[ \"$1\" = -version ] && echo \"%s version fake\" && exit 0\n", name)+script)
+}
+
+// writeFakeScript 在临时目录中生成一个原样执行的shell脚本，不响应-version
+func writeFakeScript(t *testing.T, name, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping - fake binaries require a POSIX shell")
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("Failed to write fake binary: %v", err)
+	}
+	return path
+}
+
+// TestExtractAudioContextTimeout 测试超时后终止进程组并清理输出文件
+func TestExtractAudioContextTimeout(t *testing.T) {
+	// 模拟一个写出部分输出后长时间运行、并派生了子进程的ffmpeg
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `for last; do :; done
+echo partial > "$last"
+sleep 30 &
+sleep 30
+`)
+
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "output.mp3")
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = ffmpeg.ExtractAudioContext(ctx, &ExtractAudioParams{
+		InputPath:  "input.mp4",
+		OutputPath: outputPath,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected error wrapping context.DeadlineExceeded, got %v", err)
+	}
+
+	// 进程组被终止后应当很快返回，而不是等待孙进程结束
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("ExtractAudioContext returned after %v, process group was not killed", elapsed)
+	}
+
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Fatalf("Expected partial output %s to be removed", outputPath)
+	}
+}
+
+// TestExtractAudioContextCancelKeepsExistingOutput 测试在ffmpeg打开输出之前取消时不删除已存在的输出文件
+func TestExtractAudioContextCancelKeepsExistingOutput(t *testing.T) {
+	// 模拟仍在分析输入、尚未写出输出的ffmpeg
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `sleep 30
+`)
+
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "output.mp3")
+	if err := os.WriteFile(outputPath, []byte("keep"), 0644); err != nil {
+		t.Fatalf("Failed to write existing output: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	err = ffmpeg.ExtractAudioContext(ctx, &ExtractAudioParams{
+		InputPath:  "input.mp4",
+		OutputPath: outputPath,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected error wrapping context.DeadlineExceeded, got %v", err)
+	}
+
+	if data, err := os.ReadFile(outputPath); err != nil || string(data) != "keep" {
+		t.Fatalf("Expected pre-existing output to be kept, got %q, %v", data, err)
+	}
+}
+
+// TestSplitVideoContextCancel 测试取消后只清理本次生成的分段文件
+func TestSplitVideoContextCancel(t *testing.T) {
+	outputDir := t.TempDir()
+
+	// 运行前已存在的同前缀文件不应被删除
+	existingPath := filepath.Join(outputDir, "segment_old.mp4")
+	if err := os.WriteFile(existingPath, []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to write existing segment: %v", err)
+	}
+
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `for last; do :; done
+echo partial > "$(dirname "$last")/segment_000.mp4"
+sleep 30
+`)
+
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err = ffmpeg.SplitVideoContext(ctx, &SplitVideoParams{
+		InputPath:    "input.mp4",
+		OutputDir:    outputDir,
+		SegmentTime:  10,
+		OutputPrefix: "segment_",
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected error wrapping context.Canceled, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(outputDir, "segment_000.mp4")); !os.IsNotExist(err) {
+		t.Fatal("Expected partial segment to be removed")
+	}
+	if _, err := os.Stat(existingPath); err != nil {
+		t.Fatalf("Expected pre-existing file to be kept: %v", err)
+	}
+	if entries, _ := os.ReadDir(outputDir); len(entries) != 1 {
+		t.Fatalf("Expected only the pre-existing file to be left, got %v", entries)
+	}
+}
+
+// TestLogger 测试注入的日志记录器记录命令行、退出状态和stderr
+func TestLogger(t *testing.T) {
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `echo "Unknown encoder 'libmp3lame'" >&2
+exit 1
+`)
+
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	var logs bytes.Buffer
+	ffmpeg.SetLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	err = ffmpeg.ExtractAudio(&ExtractAudioParams{
+		InputPath:  "input.mp4",
+		OutputPath: filepath.Join(t.TempDir(), "output.mp3"),
+	})
+	if err == nil {
+		t.Fatal("Expected ExtractAudio to fail")
+	}
+
+	output := logs.String()
+	for _, want := range []string{
+		"level=DEBUG msg=\"running command\"",
+		"input.mp4",
+		"level=DEBUG msg=stderr",
+		"level=ERROR msg=\"command failed\"",
+		"exit_code=1",
+		"kind=\"unsupported codec\"",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected logs to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+// TestProgressPipe 测试通过-progress管道接收进度，并用ffprobe获取的时长计算百分比
+func TestProgressPipe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping - progress is read from an extra file descriptor")
+	}
+
+	// 第一个块被拆成两次写入，验证跨读取的块能被正确拼接
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `[ "$1" = "-progress" ] && [ "$2" = "pipe:3" ] && [ "$3" = "-nostats" ] || exit 2
+printf 'frame=50\nout_ti' >&3
+sleep 0.1
+printf 'me_us=30000000\nspeed=1.5x\nprogress=continue\n' >&3
+printf 'frame=100\nout_time_us=60000000\nspeed=1.5x\nprogress=end\n' >&3
+`)
+
+	var progresses []Progress
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, writeFakeFFprobe(t), func(progress *Progress) {
+		progresses = append(progresses, *progress)
+	})
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	err = ffmpeg.ExtractAudio(&ExtractAudioParams{
+		InputPath:  "input.mp4",
+		OutputPath: filepath.Join(t.TempDir(), "output.mp3"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to extract audio: %v", err)
+	}
+
+	if len(progresses) != 3 {
+		t.Fatalf("Expected 3 progress callbacks, got %d: %+v", len(progresses), progresses)
+	}
+	// testdata/probe.json中的时长为120.12秒
+	first := progresses[0]
+	if first.Frame != 50 || first.Current != 30000 || first.Total != 120120 || first.Percentage < 24.9 || first.Percentage > 25 {
+		t.Fatalf("Unexpected first progress: %+v", first)
+	}
+	if first.ETA != time.Duration(float64(90120*time.Millisecond)/1.5) {
+		t.Fatalf("Unexpected ETA: %v", first.ETA)
+	}
+	if last := progresses[2]; last.Status != "completed" || last.Percentage != 100 {
+		t.Fatalf("Unexpected final progress: %+v", last)
+	}
+}
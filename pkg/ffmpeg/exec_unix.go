@@ -0,0 +1,43 @@
+//go:build !windows
+
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup 让子进程成为新进程组的组长，便于整组终止
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// killProcessGroup 终止子进程及其所在进程组中的所有进程
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
+
+// progressPipe 创建用于接收-progress输出的管道，写端作为额外的文件描述符传给子进程
+// 返回读端、写端以及传给ffmpeg的pipe:N地址
+func progressPipe(cmd *exec.Cmd) (*os.File, *os.File, string, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	cmd.ExtraFiles = append(cmd.ExtraFiles, w)
+	// ExtraFiles中的第i个文件在子进程中的描述符为3+i
+	return r, w, fmt.Sprintf("pipe:%d", 2+len(cmd.ExtraFiles)), nil
+}
@@ -0,0 +1,46 @@
+//go:build windows
+
+package ffmpeg
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// setProcessGroup 为子进程创建新的进程组
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+// killProcessGroup 通过taskkill终止子进程及其派生的整个进程树
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+	if err := kill.Run(); err != nil {
+		// taskkill不可用时退化为只终止子进程本身
+		return cmd.Process.Kill()
+	}
+	return nil
+}
+
+// progressPipe 创建用于接收-progress输出的管道
+// Windows不支持额外的文件描述符，只能在标准输出空闲时借用pipe:1；
+// 标准输出已被占用时返回nil，此时不回调进度
+func progressPipe(cmd *exec.Cmd) (*os.File, *os.File, string, error) {
+	if cmd.Stdout != nil {
+		return nil, nil, "", nil
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	cmd.Stdout = w
+	return r, w, "pipe:1", nil
+}
@@ -0,0 +1,181 @@
+package ffmpeg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// extractDirPrefix 释放目录名前缀，目录名后接摘要前缀以区分不同版本的二进制文件
+const extractDirPrefix = "linker-ffmpeg-"
+
+// verifiedFiles 本进程内已校验通过的文件，键为文件路径，值为校验时的摘要、大小和修改时间
+// 只保存在内存中，避免同一进程多次创建实例时重复计算摘要
+var (
+	verifiedMu    sync.Mutex
+	verifiedFiles = make(map[string]string)
+)
+
+// embeddedBinary 描述一个待释放的内置二进制文件
+type embeddedBinary struct {
+	name   string        // 文件名（不含.exe后缀）
+	data   func() []byte // 获取解压后的二进制数据
+	digest string        // 期望的SHA-256摘要，十六进制小写
+}
+
+// extractBinary 将内置二进制文件释放到extractPath下以摘要命名的子目录中
+// 已存在且摘要一致的文件直接复用。
+// 否则在文件锁保护下写入临时文件，校验后原子重命名。
+// 多个进程同时释放时不会读到不完整的文件。
+func extractBinary(extractPath string, binary embeddedBinary) (string, error) {
+	if len(binary.digest) != sha256.Size*2 {
+		return "", fmt.Errorf("embedded %s binary has no valid sha256 digest", binary.name)
+	}
+
+	// 确定释放目录
+	baseDir := extractPath
+	if baseDir == "" {
+		baseDir = os.TempDir()
+	}
+	outputDir := filepath.Join(baseDir, extractDirPrefix+binary.digest[:16])
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create extract directory: %w", err)
+	}
+
+	fileName := binary.name
+	if runtime.GOOS == "windows" {
+		fileName += ".exe"
+	}
+	outputPath := filepath.Join(outputDir, fileName)
+
+	// 已存在且校验通过时直接复用
+	if isVerified(outputPath, binary.digest) {
+		return outputPath, nil
+	}
+
+	// 加锁后再次检查，其他进程可能已经完成释放
+	unlock, err := lockPath(outputPath + ".lock")
+	if err != nil {
+		return "", fmt.Errorf("failed to lock extract directory: %w", err)
+	}
+	defer unlock()
+
+	if isVerified(outputPath, binary.digest) {
+		return outputPath, nil
+	}
+
+	// 校验内置数据本身
+	data := binary.data()
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != binary.digest {
+		return "", fmt.Errorf("embedded %s binary does not match its sha256 digest", binary.name)
+	}
+
+	// 写入临时文件后重命名，避免其他进程看到写了一半的文件
+	tempFile, err := os.CreateTemp(outputDir, fileName+".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create output file: %w", err)
+	}
+	tempPath := tempFile.Name()
+	defer os.Remove(tempPath)
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return "", fmt.Errorf("failed to write file content: %w", err)
+	}
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		return "", fmt.Errorf("failed to write file content: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return "", fmt.Errorf("failed to write file content: %w", err)
+	}
+
+	// 设置执行权限
+	if runtime.GOOS != "windows" {
+		if err := os.Chmod(tempPath, 0755); err != nil {
+			return "", fmt.Errorf("failed to set executable permission: %w", err)
+		}
+	}
+
+	// Windows上不能覆盖已存在的文件，先删除校验失败的旧文件
+	os.Remove(outputPath)
+	if err := os.Rename(tempPath, outputPath); err != nil {
+		return "", fmt.Errorf("failed to rename extracted file: %w", err)
+	}
+	markVerified(outputPath, binary.digest)
+
+	return outputPath, nil
+}
+
+// isVerified 判断path是否为摘要为digest的文件
+// 本进程内已校验过且大小和修改时间未变化时直接信任，否则重新计算摘要
+func isVerified(path, digest string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	verifiedMu.Lock()
+	stamp, ok := verifiedFiles[path]
+	verifiedMu.Unlock()
+	if ok && stamp == verifiedStamp(digest, info) {
+		return true
+	}
+	if fileDigest(path) != digest {
+		return false
+	}
+	markVerified(path, digest)
+	return true
+}
+
+// markVerified 在内存中记录path已校验通过
+func markVerified(path, digest string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	verifiedMu.Lock()
+	verifiedFiles[path] = verifiedStamp(digest, info)
+	verifiedMu.Unlock()
+}
+
+// verifiedStamp 返回校验记录，由摘要、文件大小和修改时间组成
+func verifiedStamp(digest string, info os.FileInfo) string {
+	return fmt.Sprintf("%s %d %d", digest, info.Size(), info.ModTime().UnixNano())
+}
+
+// fileDigest 计算文件的SHA-256摘要，文件不存在或读取失败时返回空字符串
+func fileDigest(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// lockPath 打开并独占锁定指定的锁文件，返回释放锁的函数
+func lockPath(path string) (func(), error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return func() {
+		unlockFile(file)
+		file.Close()
+	}, nil
+}
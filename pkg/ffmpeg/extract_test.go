@@ -0,0 +1,150 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newTestEmbeddedBinary 构造带正确摘要的模拟内置二进制文件
+func newTestEmbeddedBinary(content []byte) embeddedBinary {
+	sum := sha256.Sum256(content)
+	return embeddedBinary{
+		name:   "ffmpeg",
+		data:   func() []byte { return content },
+		digest: hex.EncodeToString(sum[:]),
+	}
+}
+
+// TestExtractBinary 测试释放到以摘要命名的子目录，并替换损坏的旧文件
+func TestExtractBinary(t *testing.T) {
+	content := []byte("#!/bin/sh\necho ffmpeg version test\n")
+	binary := newTestEmbeddedBinary(content)
+	extractPath := t.TempDir()
+
+	path, err := extractBinary(extractPath, binary)
+	if err != nil {
+		t.Fatalf("Failed to extract binary: %v", err)
+	}
+	if dir := filepath.Base(filepath.Dir(path)); dir != extractDirPrefix+binary.digest[:16] {
+		t.Fatalf("Expected content-hash directory, got %s", dir)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, content) {
+		t.Fatalf("Extracted content mismatch: %q", data)
+	}
+
+	// 模拟上次写入被截断，再次释放时应重新写入
+	if err := os.WriteFile(path, content[:5], 0755); err != nil {
+		t.Fatalf("Failed to truncate binary: %v", err)
+	}
+	if _, err := extractBinary(extractPath, binary); err != nil {
+		t.Fatalf("Failed to re-extract binary: %v", err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, content) {
+		t.Fatalf("Truncated binary was not replaced: %q", data)
+	}
+
+	// 不应遗留临时文件
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Fatalf("Temporary file left behind: %s", entry.Name())
+		}
+	}
+}
+
+// TestExtractBinaryReplacedFile 测试文件被替换为大小和修改时间相同的内容时，新进程会重新校验并释放
+func TestExtractBinaryReplacedFile(t *testing.T) {
+	content := []byte("#!/bin/sh\necho ffmpeg version test\n")
+	binary := newTestEmbeddedBinary(content)
+	extractPath := t.TempDir()
+
+	path, err := extractBinary(extractPath, binary)
+	if err != nil {
+		t.Fatalf("Failed to extract binary: %v", err)
+	}
+	if _, err := os.Stat(path + ".verified"); !os.IsNotExist(err) {
+		t.Fatalf("Expected no verification stamp next to the binary")
+	}
+
+	// 本进程内复用时不应再读取内置数据
+	data := binary.data
+	binary.data = func() []byte {
+		t.Fatal("Embedded data read for an already verified binary")
+		return nil
+	}
+	if _, err := extractBinary(extractPath, binary); err != nil {
+		t.Fatalf("Failed to reuse binary: %v", err)
+	}
+
+	// 模拟其他进程替换文件并恢复修改时间，新进程没有内存中的校验记录
+	info, _ := os.Stat(path)
+	if err := os.WriteFile(path, bytes.ToUpper(content), 0755); err != nil {
+		t.Fatalf("Failed to replace binary: %v", err)
+	}
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatalf("Failed to restore modification time: %v", err)
+	}
+	verifiedMu.Lock()
+	delete(verifiedFiles, path)
+	verifiedMu.Unlock()
+
+	binary.data = data
+	if _, err := extractBinary(extractPath, binary); err != nil {
+		t.Fatalf("Failed to re-extract binary: %v", err)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, content) {
+		t.Fatalf("Replaced binary was trusted: %q", got)
+	}
+}
+
+// TestExtractBinaryDigestMismatch 测试内置数据与摘要不一致时拒绝释放
+func TestExtractBinaryDigestMismatch(t *testing.T) {
+	binary := newTestEmbeddedBinary([]byte("original"))
+	binary.data = func() []byte { return []byte("tampered") }
+
+	if _, err := extractBinary(t.TempDir(), binary); err == nil {
+		t.Fatal("Expected error for binary that does not match its digest")
+	}
+
+	binary.digest = ""
+	if _, err := extractBinary(t.TempDir(), binary); err == nil {
+		t.Fatal("Expected error for binary without digest")
+	}
+}
+
+// TestExtractBinaryConcurrent 测试并发释放同一个二进制文件
+func TestExtractBinaryConcurrent(t *testing.T) {
+	content := bytes.Repeat([]byte("ffmpeg"), 1<<20)
+	binary := newTestEmbeddedBinary(content)
+	extractPath := t.TempDir()
+
+	var wg sync.WaitGroup
+	paths := make([]string, 8)
+	errs := make([]error, len(paths))
+	for i := range paths {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			paths[i], errs[i] = extractBinary(extractPath, binary)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range paths {
+		if errs[i] != nil {
+			t.Fatalf("Failed to extract binary: %v", errs[i])
+		}
+		if paths[i] != paths[0] {
+			t.Fatalf("Expected identical paths, got %s and %s", paths[i], paths[0])
+		}
+	}
+	if fileDigest(paths[0]) != binary.digest {
+		t.Fatal("Extracted binary does not match its digest")
+	}
+}
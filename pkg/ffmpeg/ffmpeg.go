@@ -1,142 +1,55 @@
+// Package ffmpeg 封装ffmpeg/ffprobe命令行，提供转码、分段、抽帧、精灵图、预览动画和HLS打包等操作
+//
+// 所有操作都接收context.Context用于取消和超时：最早的ExtractAudio、SplitVideo、ExtractKeyFrames和
+// GetVideoDuration为保持兼容保留不带ctx的签名，并各自提供以Context结尾的版本；
+// 其余方法只提供把ctx作为第一个参数的版本，不需要取消时传入context.Background()
 package ffmpeg
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
 	"runtime"
-	"strconv"
-	"strings"
+	"time"
 
-	ffmpeg_go "github.com/u2takey/ffmpeg-go"
 	"github.com/yxx1912008/linker-ffmpeg-go/internal/ffmpeg"
 )
 
 // extractFFmpeg 提取FFmpeg二进制文件到指定路径
-// 如果extractPath为空，则使用临时目录
+// 如果extractPath为空，则使用临时目录；当前平台没有内置二进制时返回ErrNoEmbeddedBinary
 func extractFFmpeg(extractPath string) (string, error) {
-	// 确定释放目录
-	var outputDir string
-	if extractPath != "" {
-		outputDir = extractPath
-		// 确保释放目录存在
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return "", fmt.Errorf("failed to create extract directory: %w", err)
-		}
-	} else {
-		// 使用临时目录
-		outputDir = os.TempDir()
-	}
-
-	// 根据平台获取FFmpeg文件名
-	ffmpegFileName := "ffmpeg"
-	if runtime.GOOS == "windows" {
-		ffmpegFileName += ".exe"
-	}
-	// 构建输出路径
-	outputPath := filepath.Join(outputDir, ffmpegFileName)
-
-	// 检查文件是否已存在
-	if _, err := os.Stat(outputPath); err == nil {
-		// 文件已存在，返回路径
-		return outputPath, nil
-	}
-
-	// 获取内部嵌入的FFmpeg二进制数据
-	ffmpegBinary := ffmpeg.GetFFmpegBinary()
-	if len(ffmpegBinary) == 0 {
-		// 如果二进制数据为空，返回空路径，让用户自行设置
-		return "", nil
-	}
-
-	// 创建输出文件
-	outFile, err := os.Create(outputPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create output file: %w", err)
-	}
-	defer outFile.Close()
-
-	// 写入二进制数据
-	_, err = outFile.Write(ffmpegBinary)
-	if err != nil {
-		return "", fmt.Errorf("failed to write file content: %w", err)
+	// 检查当前平台是否内置了二进制文件
+	if !ffmpeg.HasFFmpegBinary() {
+		return "", fmt.Errorf("%w: ffmpeg for %s/%s", ErrNoEmbeddedBinary, runtime.GOOS, runtime.GOARCH)
 	}
 
-	// 设置执行权限
-	if runtime.GOOS != "windows" {
-		if err := outFile.Chmod(0755); err != nil {
-			return "", fmt.Errorf("failed to set executable permission: %w", err)
-		}
-	}
-
-	return outputPath, nil
+	return extractBinary(extractPath, embeddedBinary{
+		name:   "ffmpeg",
+		data:   ffmpeg.GetFFmpegBinary,
+		digest: ffmpeg.FFmpegSHA256(),
+	})
 }
 
 // extractFFprobe 提取FFprobe二进制文件到指定路径
-// 如果extractPath为空，则使用临时目录
+// 如果extractPath为空，则使用临时目录；当前平台没有内置二进制时返回ErrNoEmbeddedBinary
 func extractFFprobe(extractPath string) (string, error) {
-	// 确定释放目录
-	var outputDir string
-	if extractPath != "" {
-		outputDir = extractPath
-		// 确保释放目录存在
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return "", fmt.Errorf("failed to create extract directory: %w", err)
-		}
-	} else {
-		// 使用临时目录
-		outputDir = os.TempDir()
-	}
-
-	// 根据平台获取FFprobe文件名
-	ffprobeFileName := "ffprobe"
-	if runtime.GOOS == "windows" {
-		ffprobeFileName += ".exe"
-	}
-	// 构建输出路径
-	outputPath := filepath.Join(outputDir, ffprobeFileName)
-
-	// 检查文件是否已存在
-	if _, err := os.Stat(outputPath); err == nil {
-		// 文件已存在，返回路径
-		return outputPath, nil
-	}
-
-	// 获取内部嵌入的FFprobe二进制数据
-	ffprobeBinary := ffmpeg.GetFFprobeBinary()
-	if len(ffprobeBinary) == 0 {
-		// 如果二进制数据为空，返回空路径，让用户自行设置
-		return "", nil
-	}
-
-	// 创建输出文件
-	outFile, err := os.Create(outputPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create output file: %w", err)
+	// 检查当前平台是否内置了二进制文件
+	if !ffmpeg.HasFFprobeBinary() {
+		return "", fmt.Errorf("%w: ffprobe for %s/%s", ErrNoEmbeddedBinary, runtime.GOOS, runtime.GOARCH)
 	}
-	defer outFile.Close()
 
-	// 写入二进制数据
-	_, err = outFile.Write(ffprobeBinary)
-	if err != nil {
-		return "", fmt.Errorf("failed to write file content: %w", err)
-	}
-
-	// 设置执行权限
-	if runtime.GOOS != "windows" {
-		if err := outFile.Chmod(0755); err != nil {
-			return "", fmt.Errorf("failed to set executable permission: %w", err)
-		}
-	}
-
-	return outputPath, nil
+	return extractBinary(extractPath, embeddedBinary{
+		name:   "ffprobe",
+		data:   ffmpeg.GetFFprobeBinary,
+		digest: ffmpeg.FFprobeSHA256(),
+	})
 }
 
 // NewFFmpeg 创建并初始化FFmpeg工具
-// 使用默认的临时目录来释放FFmpeg二进制文件
+// 依次从FFMPEG_PATH/FFPROBE_PATH环境变量、$PATH和内置二进制文件中查找ffmpeg，
+// 需要释放内置二进制文件时使用默认的临时目录
 // 参数:
 //
 //	callback: 进度回调函数，用于接收处理进度信息
@@ -144,7 +57,8 @@ func extractFFprobe(extractPath string) (string, error) {
 // 返回值:
 //
 //	*FFmpeg: FFmpeg工具实例
-//	error: 如果初始化失败，返回错误信息
+//	error: 如果初始化失败，返回错误信息；找不到可用的ffmpeg时返回包装了ErrBinaryNotFound的错误，
+//	       当前平台也没有内置二进制时同时包装了ErrNoEmbeddedBinary
 //
 // 示例:
 //
@@ -156,6 +70,7 @@ func NewFFmpeg(callback ProgressCallback) (*FFmpeg, error) {
 }
 
 // NewFFmpegWithExtractPath 使用指定释放路径创建FFmpeg工具
+// 查找顺序与NewFFmpeg相同，只有环境变量和$PATH中都没有可用的ffmpeg时才释放内置二进制文件
 // 参数:
 //
 //	extractPath: 二进制文件释放路径，为空则使用临时目录
@@ -164,30 +79,90 @@ func NewFFmpeg(callback ProgressCallback) (*FFmpeg, error) {
 // 返回值:
 //
 //	*FFmpeg: FFmpeg工具实例
-//	error: 如果初始化失败，返回错误信息
+//	error: 如果初始化失败，返回错误信息；当前平台没有内置二进制时包装了ErrNoEmbeddedBinary
 //
 // 示例:
 //
 //	ffmpeg, err := ffmpeg.NewFFmpegWithExtractPath("/tmp/ffmpeg", callback)
 func NewFFmpegWithExtractPath(extractPath string, callback ProgressCallback) (*FFmpeg, error) {
-	// 提取FFmpeg到指定路径
-	ffmpegPath, err := extractFFmpeg(extractPath)
+	return NewFFmpegWithOptions(&Options{
+		ExtractPath: extractPath,
+		Callback:    callback,
+	})
+}
+
+// NewFFmpegWithOptions 使用选项创建FFmpeg工具
+// ffmpeg和ffprobe分别按以下顺序查找，并通过执行-version校验：
+//
+//  1. Options中显式指定的路径（校验失败时直接返回错误）
+//  2. FFMPEG_PATH/FFPROBE_PATH环境变量
+//  3. $PATH中的ffmpeg/ffprobe
+//  4. 释放内置的二进制文件
+//
+// 实际使用的来源记录在FFmpegSource和FFprobeSource字段中；
+// 找不到ffprobe时不会返回错误，FFprobePath留空
+//
+// 参数:
+//
+//	opts: 创建选项，可以为nil
+//
+// 返回值:
+//
+//	*FFmpeg: FFmpeg工具实例
+//	error: 如果找不到可用的ffmpeg，返回包装了ErrBinaryNotFound的错误
+//
+// 示例:
+//
+//	ffmpeg, err := ffmpeg.NewFFmpegWithOptions(&ffmpeg.Options{
+//	    ExtractPath: "/var/lib/myapp/bin",
+//	    Callback:    callback,
+//	})
+//	fmt.Println(ffmpeg.FFmpegSource) // "path"
+func NewFFmpegWithOptions(opts *Options) (*FFmpeg, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	f := &FFmpeg{
+		ExtractPath: opts.ExtractPath,
+		Callback:    opts.Callback,
+		Logger:      opts.Logger,
+	}
+	logger := f.logger()
+
+	ffmpegPath, ffmpegSource, err := resolveBinary(binaryLookup{
+		name:     "ffmpeg",
+		explicit: opts.FFmpegPath,
+		envVar:   "FFMPEG_PATH",
+		extract:  extractFFmpeg,
+	}, opts.ExtractPath, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	// 提取FFprobe到指定路径
-	ffprobePath, err := extractFFprobe(extractPath)
+	ffprobePath, ffprobeSource, err := resolveBinary(binaryLookup{
+		name:     "ffprobe",
+		explicit: opts.FFprobePath,
+		envVar:   "FFPROBE_PATH",
+		extract:  extractFFprobe,
+	}, opts.ExtractPath, logger)
 	if err != nil {
-		return nil, err
+		// 显式指定的ffprobe无效时报错，否则只记录警告
+		if opts.FFprobePath != "" {
+			return nil, err
+		}
+		logger.Warn("ffprobe not found", "error", err)
 	}
 
-	return &FFmpeg{
-		FFmpegPath:  ffmpegPath,
-		FFprobePath: ffprobePath,
-		ExtractPath: extractPath,
-		Callback:    callback,
-	}, nil
+	f.FFmpegPath = ffmpegPath
+	f.FFmpegSource = ffmpegSource
+	f.FFprobePath = ffprobePath
+	f.FFprobeSource = ffprobeSource
+	logger.Info("resolved ffmpeg binaries",
+		"ffmpeg", ffmpegPath, "ffmpeg_source", string(ffmpegSource),
+		"ffprobe", ffprobePath, "ffprobe_source", string(ffprobeSource))
+
+	return f, nil
 }
 
 // NewFFmpegWithPath 使用指定路径的FFmpeg二进制文件创建FFmpeg工具
@@ -200,29 +175,32 @@ func NewFFmpegWithExtractPath(extractPath string, callback ProgressCallback) (*F
 // 返回值:
 //
 //	*FFmpeg: FFmpeg工具实例
-//	error: 如果FFmpeg路径不存在或无效，返回错误信息
+//	error: 如果路径不存在、不可执行或执行-version的输出不是ffmpeg/ffprobe，返回错误信息
 //
 // 示例:
 //
 //	ffmpeg, err := ffmpeg.NewFFmpegWithPath("/usr/bin/ffmpeg", "/usr/bin/ffprobe", callback)
 func NewFFmpegWithPath(ffmpegPath string, ffprobePath string, callback ProgressCallback) (*FFmpeg, error) {
-	// 检查指定的FFmpeg路径是否存在
-	if _, err := os.Stat(ffmpegPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("ffmpeg executable not found at: %s", ffmpegPath)
+	// 与NewFFmpegWithOptions显式指定路径时一样，执行-version校验二进制文件是否可用
+	if err := validateBinary(ffmpegPath, "ffmpeg"); err != nil {
+		return nil, err
 	}
-
-	// 检查指定的FFprobe路径是否存在（如果提供了路径）
 	if ffprobePath != "" {
-		if _, err := os.Stat(ffprobePath); os.IsNotExist(err) {
-			return nil, fmt.Errorf("ffprobe executable not found at: %s", ffprobePath)
+		if err := validateBinary(ffprobePath, "ffprobe"); err != nil {
+			return nil, err
 		}
 	}
 
-	return &FFmpeg{
-		FFmpegPath:  ffmpegPath,
-		FFprobePath: ffprobePath,
-		Callback:    callback,
-	}, nil
+	f := &FFmpeg{
+		FFmpegPath:   ffmpegPath,
+		FFprobePath:  ffprobePath,
+		Callback:     callback,
+		FFmpegSource: BinarySourceExplicit,
+	}
+	if ffprobePath != "" {
+		f.FFprobeSource = BinarySourceExplicit
+	}
+	return f, nil
 }
 
 // SetFFmpegPath 设置FFmpeg二进制文件路径
@@ -265,6 +243,23 @@ func (f *FFmpeg) GetFFprobePath() string {
 	return f.FFprobePath
 }
 
+// SetLogger 设置日志记录器
+// 设置后会记录执行的命令行、耗时、退出状态及stderr输出；为nil时不输出任何日志
+// 参数:
+//
+//	logger: 日志记录器
+//
+// 返回值:
+//
+//	无
+//
+// 示例:
+//
+//	ffmpeg.SetLogger(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+func (f *FFmpeg) SetLogger(logger *slog.Logger) {
+	f.Logger = logger
+}
+
 // SetProgressCallback 设置进度回调函数
 // 参数:
 //
@@ -277,58 +272,8 @@ func (f *FFmpeg) SetProgressCallback(callback ProgressCallback) {
 	f.Callback = callback
 }
 
-// 解析ffmpeg输出的进度信息
-func (f *FFmpeg) parseProgress(output string) {
-	if f.Callback == nil {
-		return
-	}
-
-	// 匹配时间进度信息
-	timeRegex := regexp.MustCompile(`time=(\d+):(\d+):(\d+)\.(\d+)`)
-	durationRegex := regexp.MustCompile(`Duration: (\d+):(\d+):(\d+)\.(\d+)`)
-
-	timeMatches := timeRegex.FindStringSubmatch(output)
-	durationMatches := durationRegex.FindStringSubmatch(output)
-
-	if len(timeMatches) < 5 || len(durationMatches) < 5 {
-		return
-	}
-
-	// 解析当前时间
-	currentHours, _ := strconv.Atoi(timeMatches[1])
-	currentMinutes, _ := strconv.Atoi(timeMatches[2])
-	currentSeconds, _ := strconv.Atoi(timeMatches[3])
-	currentMilliseconds, _ := strconv.Atoi(timeMatches[4])
-
-	current := int64(currentHours*3600*1000 + currentMinutes*60*1000 + currentSeconds*1000 + currentMilliseconds*10)
-
-	// 解析总时长
-	totalHours, _ := strconv.Atoi(durationMatches[1])
-	totalMinutes, _ := strconv.Atoi(durationMatches[2])
-	totalSeconds, _ := strconv.Atoi(durationMatches[3])
-	totalMilliseconds, _ := strconv.Atoi(durationMatches[4])
-
-	total := int64(totalHours*3600*1000 + totalMinutes*60*1000 + totalSeconds*1000 + totalMilliseconds*10)
-
-	// 计算百分比
-	percentage := 0.0
-	if total > 0 {
-		percentage = (float64(current) / float64(total)) * 100
-		if percentage > 100 {
-			percentage = 100
-		}
-	}
-
-	// 调用回调函数
-	f.Callback(&Progress{
-		Percentage: percentage,
-		Current:    current,
-		Total:      total,
-		Status:     "processing",
-	})
-}
-
 // ExtractAudio 从视频文件中提取音频流
+// 未指定Codec时根据输出文件扩展名选择编码，如.mp3使用mp3、.m4a和.aac使用aac、.wav使用pcm
 // 参数:
 //
 //	params: 提取音频流的参数配置
@@ -344,64 +289,71 @@ func (f *FFmpeg) parseProgress(output string) {
 //	    OutputPath: "output.mp3",
 //	})
 func (f *FFmpeg) ExtractAudio(params *ExtractAudioParams) error {
-	// 设置环境变量指定ffmpeg路径
-	origFfmpegPath := os.Getenv("FFMPEG_PATH")
-	os.Setenv("FFMPEG_PATH", f.FFmpegPath)
-	defer os.Setenv("FFMPEG_PATH", origFfmpegPath)
-
-	// 创建命令
-	cmd := exec.Command(f.FFmpegPath, "-y", "-i", params.InputPath, "-vn", "-acodec", "libmp3lame", params.OutputPath)
-
-	//打印命令
-	fmt.Println(cmd.String())
-
-	// 处理输出
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return err
-	}
+	return f.ExtractAudioContext(context.Background(), params)
+}
 
-	// 启动命令
-	if err := cmd.Start(); err != nil {
+// ExtractAudioContext 从视频文件中提取音频流，支持通过ctx取消或设置超时
+// ctx被取消或超时时会终止ffmpeg进程组并删除不完整的输出文件，
+// 返回的错误包装了context.Canceled或context.DeadlineExceeded
+// 指定了AudioTrack、Language或直接复制时会先通过ffprobe获取音轨信息，找不到音轨时返回包装了ErrStreamNotFound的错误
+// 设置了Input或Output时通过stdin读取输入、通过stdout写入输出，需要定位的容器不能写入Output
+// 参数:
+//
+//	ctx: 控制命令生命周期的上下文
+//	params: 提取音频流的参数配置
+//
+// 返回值:
+//
+//	error: 如果提取失败，返回错误信息
+//
+// 示例:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//	defer cancel()
+//	err := ffmpeg.ExtractAudioContext(ctx, &ffmpeg.ExtractAudioParams{
+//	    InputPath:        "input.mkv",
+//	    OutputPath:       "output.m4a",
+//	    Language:         "eng",
+//	    CopyIfCompatible: true,
+//	})
+func (f *FFmpeg) ExtractAudioContext(ctx context.Context, params *ExtractAudioParams) error {
+	if err := params.Validate(); err != nil {
 		return err
 	}
 
-	// 读取输出并解析进度
-	outputChan := make(chan string)
-
-	// 读取stderr
-	go func() {
-		buf := make([]byte, 1024)
-		for {
-			n, err := stderr.Read(buf)
-			if err != nil {
-				close(outputChan)
-				return
-			}
-			if n > 0 {
-				outputChan <- string(buf[:n])
-			}
+	// 选择音轨或判断能否直接复制时需要先获取音轨信息
+	var stream *StreamInfo
+	if params.needsProbe() {
+		info, err := f.Probe(ctx, params.InputPath)
+		if err != nil {
+			return err
 		}
-	}()
-
-	// 解析输出
-	go func() {
-		for output := range outputChan {
-			f.parseProgress(output)
+		stream, err = selectAudioStream(info, params.AudioTrack, params.Language)
+		if err != nil {
+			return err
 		}
-	}()
+	}
 
-	// 等待命令完成
-	if err := cmd.Wait(); err != nil {
+	args, err := buildExtractAudioArgs(params, stream)
+	if err != nil {
 		return err
 	}
 
-	// 发送完成进度
-	if f.Callback != nil {
-		f.Callback(&Progress{
-			Percentage: 100,
-			Status:     "completed",
-		})
+	cleanup := removeNewOutputFile(params.OutputPath)
+	err = f.run(ctx, &runOptions{
+		binary:   f.FFmpegPath,
+		args:     args,
+		stdin:    params.Input,
+		stdout:   params.Output,
+		progress: true,
+		total:    f.rangeDuration(ctx, params.InputPath, params.timeRange()),
+	})
+	if err != nil {
+		// 取消或超时后删除本次新建的不完整输出文件
+		if ctx.Err() != nil && params.Output == nil {
+			cleanup()
+		}
+		return err
 	}
 
 	return nil
@@ -414,7 +366,7 @@ func (f *FFmpeg) ExtractAudio(params *ExtractAudioParams) error {
 //
 // 返回值:
 //
-//	[]string: 分段后的视频文件路径列表
+//	[]Segment: 本次生成的分段列表，包含文件路径、实际开始和结束时间、时长及文件大小
 //	error: 如果分段失败，返回错误信息
 //
 // 示例:
@@ -425,120 +377,95 @@ func (f *FFmpeg) ExtractAudio(params *ExtractAudioParams) error {
 //	    SegmentTime:  10, // 每10秒一个分段
 //	    OutputPrefix: "segment_",
 //	})
-func (f *FFmpeg) SplitVideo(params *SplitVideoParams) ([]string, error) {
-	// 设置环境变量指定ffmpeg路径
-	origFfmpegPath := os.Getenv("FFMPEG_PATH")
-	os.Setenv("FFMPEG_PATH", f.FFmpegPath)
-	defer os.Setenv("FFMPEG_PATH", origFfmpegPath)
+func (f *FFmpeg) SplitVideo(params *SplitVideoParams) ([]Segment, error) {
+	return f.SplitVideoContext(context.Background(), params)
+}
 
-	// 确保输出目录存在
-	if err := os.MkdirAll(params.OutputDir, 0755); err != nil {
+// SplitVideoContext 将视频文件分割为多个小段，支持通过ctx取消或设置超时
+// ctx被取消或超时时会终止ffmpeg进程组并删除本次生成的分段文件，
+// 返回的错误包装了context.Canceled或context.DeadlineExceeded；
+// 按MaxSize切分时可能多次运行ffmpeg，多次缩短分段时长后仍有分段超出时返回ErrSegmentTooLarge；
+// 每次运行都报告进度，只在接受最终输出后报告一次completed
+// 参数:
+//
+//	ctx: 控制命令生命周期的上下文
+//	params: 视频分段的参数配置
+//
+// 返回值:
+//
+//	[]Segment: 本次生成的分段列表，包含文件路径、实际开始和结束时间、时长及文件大小
+//	error: 如果分段失败，返回错误信息
+//
+// 示例:
+//
+//	segments, err := ffmpeg.SplitVideoContext(ctx, &ffmpeg.SplitVideoParams{
+//	    InputPath:    "input.mp4",
+//	    OutputDir:    "/tmp/segments",
+//	    SegmentTime:  10,
+//	    OutputPrefix: "segment_",
+//	    Mode:         ffmpeg.SplitModeReencode, // 每个分段精确为10秒
+//	})
+func (f *FFmpeg) SplitVideoContext(ctx context.Context, params *SplitVideoParams) ([]Segment, error) {
+	if err := params.Validate(); err != nil {
 		return nil, err
 	}
+	trim := params.timeRange()
 
-	// 构建输出文件名模式
-	// 注意：segment muxer只支持单个占位符，我们先使用序号
-	outputPattern := fmt.Sprintf("%s/%s%%03d.mp4", params.OutputDir, params.OutputPrefix)
-
-	// 创建命令
-	cmd := exec.Command(f.FFmpegPath, "-i", params.InputPath, "-c", "copy", "-f", "segment", "-segment_time", strconv.Itoa(params.SegmentTime), "-reset_timestamps", "1", outputPattern)
-
-	// 处理输出
-	stderr, err := cmd.StderrPipe()
+	// 计算切分方式，按场景、章节或大小切分时需要先分析输入文件
+	plan, err := f.planSplit(ctx, params)
 	if err != nil {
 		return nil, err
 	}
 
-	// 启动命令
-	if err := cmd.Start(); err != nil {
+	// 确保输出目录存在
+	if err := os.MkdirAll(params.OutputDir, 0755); err != nil {
 		return nil, err
 	}
 
-	// 读取输出并解析进度
-	outputChan := make(chan string)
-	var stderrOutput strings.Builder
+	// 按大小切分时分段时长只是根据平均码率估算的，
+	// 有分段超过MaxSize时丢弃本次生成的分段，按超出的比例缩短分段时长后重新切分；
+	// 每次切分都写入输出目录下的临时目录，被接受后才移动到输出目录，
+	// 被丢弃或取消的切分不会覆盖输出目录中已有的文件
+	total := f.rangeDuration(ctx, params.InputPath, trim)
+	for attempt := 1; ; attempt++ {
+		workDir, err := os.MkdirTemp(params.OutputDir, splitWorkDirPattern)
+		if err != nil {
+			return nil, err
+		}
+		segments, err := f.splitSegments(ctx, params, plan, total, workDir)
+		if err != nil {
+			os.RemoveAll(workDir)
+			return nil, err
+		}
 
-	go func() {
-		buf := make([]byte, 1024)
-		for {
-			n, err := stderr.Read(buf)
+		largest := largestSegment(segments)
+		if params.MaxSize == 0 || largest.Size <= params.MaxSize {
+			segments, err = f.acceptSegments(ctx, segments, params.OutputDir)
+			os.RemoveAll(workDir)
 			if err != nil {
-				close(outputChan)
-				return
-			}
-			if n > 0 {
-				output := string(buf[:n])
-				stderrOutput.WriteString(output)
-				outputChan <- output
+				return nil, err
 			}
+			f.reportCompleted()
+			return segments, nil
 		}
-	}()
-
-	// 解析输出
-	go func() {
-		for output := range outputChan {
-			f.parseProgress(output)
+		os.RemoveAll(workDir)
+		plan.interval = shrinkInterval(plan.interval, largest.Size, params.MaxSize)
+		if attempt == maxSizeAttempts || plan.interval < time.Second {
+			return nil, fmt.Errorf("%w: %s is %d bytes, max size %d bytes",
+				ErrSegmentTooLarge, filepath.Base(largest.Path), largest.Size, params.MaxSize)
 		}
-	}()
-
-	// 等待命令完成
-	if err := cmd.Wait(); err != nil {
-		return nil, fmt.Errorf("ffmpeg command failed: %w\n%s", err, stderrOutput.String())
 	}
-
-	// 发送完成进度
-	if f.Callback != nil {
-		f.Callback(&Progress{
-			Percentage: 100,
-			Status:     "completed",
-		})
-	}
-
-	// 获取分段文件列表
-	files, err := os.ReadDir(params.OutputDir)
-	if err != nil {
-		return nil, err
-	}
-
-	var segmentFiles []string
-	for _, file := range files {
-		if !file.IsDir() && strings.HasPrefix(file.Name(), params.OutputPrefix) && strings.HasSuffix(file.Name(), ".mp4") {
-			segmentFiles = append(segmentFiles, fmt.Sprintf("%s/%s", params.OutputDir, file.Name()))
-		}
-	}
-
-	// 为分段文件添加时间戳信息
-	for i, segmentPath := range segmentFiles {
-		// 计算时间戳（秒级）
-		timestamp := i * params.SegmentTime
-
-		// 获取文件目录
-		dir := filepath.Dir(segmentPath)
-
-		// 生成新文件名，包含序号和时间戳
-		newName := fmt.Sprintf("%s%03d_%d.mp4", params.OutputPrefix, i, timestamp)
-		newPath := filepath.Join(dir, newName)
-
-		// 重命名文件
-		if err := os.Rename(segmentPath, newPath); err != nil {
-			return nil, fmt.Errorf("failed to rename segment file: %w", err)
-		}
-
-		// 更新文件列表中的路径
-		segmentFiles[i] = newPath
-	}
-
-	return segmentFiles, nil
 }
 
 // ExtractKeyFrames 从视频文件中提取关键帧
+// 支持按间隔采样、提取所有I帧、按场景切换提取以及均匀提取指定帧数，见KeyFrameMode
 // 参数:
 //
 //	params: 提取关键帧的参数配置
 //
 // 返回值:
 //
-//	[]string: 提取的关键帧文件路径列表
+//	[]Frame: 提取的关键帧列表，按时间排序
 //	error: 如果提取失败，返回错误信息
 //
 // 示例:
@@ -546,96 +473,80 @@ func (f *FFmpeg) SplitVideo(params *SplitVideoParams) ([]string, error) {
 //	keyframes, err := ffmpeg.ExtractKeyFrames(&ffmpeg.ExtractKeyFramesParams{
 //	    InputPath:     "input.mp4",
 //	    OutputDir:     "/tmp/keyframes",
-//	    FrameInterval: 5, // 每5秒提取一个关键帧
+//	    FrameInterval: 5, // 每5秒采样一帧
 //	    OutputPrefix:  "keyframe_",
 //	})
-func (f *FFmpeg) ExtractKeyFrames(params *ExtractKeyFramesParams) ([]string, error) {
-	// 设置环境变量指定ffmpeg路径
-	origFfmpegPath := os.Getenv("FFMPEG_PATH")
-	os.Setenv("FFMPEG_PATH", f.FFmpegPath)
-	defer os.Setenv("FFMPEG_PATH", origFfmpegPath)
+func (f *FFmpeg) ExtractKeyFrames(params *ExtractKeyFramesParams) ([]Frame, error) {
+	return f.ExtractKeyFramesContext(context.Background(), params)
+}
 
-	// 确保输出目录存在
-	if err := os.MkdirAll(params.OutputDir, 0755); err != nil {
+// ExtractKeyFramesContext 从视频文件中提取关键帧，支持通过ctx取消或设置超时
+// ctx被取消或超时时会终止ffmpeg进程组并删除本次生成的图片，
+// 返回的错误包装了context.Canceled或context.DeadlineExceeded
+// 参数:
+//
+//	ctx: 控制命令生命周期的上下文
+//	params: 提取关键帧的参数配置
+//
+// 返回值:
+//
+//	[]Frame: 提取的关键帧列表，按时间排序
+//	error: 如果提取失败，返回错误信息
+//
+// 示例:
+//
+//	keyframes, err := ffmpeg.ExtractKeyFramesContext(ctx, &ffmpeg.ExtractKeyFramesParams{
+//	    InputPath:    "input.mp4",
+//	    OutputDir:    "/tmp/keyframes",
+//	    Mode:         ffmpeg.KeyFrameModeIFrames, // 只解码关键帧
+//	    OutputPrefix: "keyframe_",
+//	})
+func (f *FFmpeg) ExtractKeyFramesContext(ctx context.Context, params *ExtractKeyFramesParams) ([]Frame, error) {
+	if err := params.Validate(); err != nil {
 		return nil, err
 	}
+	trim := params.timeRange()
 
-	// 构建输出文件名模式
-	outputPattern := fmt.Sprintf("%s/%s%%06d.jpg", params.OutputDir, params.OutputPrefix)
-
-	// 使用ffmpeg的select过滤器结合fps过滤器，实现按时间间隔提取关键帧
-	// select='eq(pict_type,I)' 表示只选择关键帧
-	// fps=1/%d 表示每%d秒输出1帧
-	cmd := exec.Command(f.FFmpegPath, "-i", params.InputPath,
-		"-vf", fmt.Sprintf("select='eq(pict_type,I)',fps=1/%d", params.FrameInterval),
-		"-vsync", "vfr",
-		outputPattern)
-
-	// 处理输出
-	stderr, err := cmd.StderrPipe()
+	// 均匀采样指定帧数时需要先获取输入文件时长
+	length, err := f.keyFrameLength(ctx, params)
 	if err != nil {
 		return nil, err
 	}
 
-	// 启动命令
-	if err := cmd.Start(); err != nil {
+	// 确保输出目录存在
+	if err := os.MkdirAll(params.OutputDir, 0755); err != nil {
 		return nil, err
 	}
 
-	// 读取输出并解析进度
-	outputChan := make(chan string)
-	var stderrOutput strings.Builder
-
-	go func() {
-		buf := make([]byte, 1024)
-		for {
-			n, err := stderr.Read(buf)
-			if err != nil {
-				close(outputChan)
-				return
-			}
-			if n > 0 {
-				output := string(buf[:n])
-				stderrOutput.WriteString(output)
-				outputChan <- output
-			}
-		}
-	}()
-
-	// 解析输出
-	go func() {
-		for output := range outputChan {
-			f.parseProgress(output)
-		}
-	}()
-
-	// 等待命令完成
-	if err := cmd.Wait(); err != nil {
-		return nil, fmt.Errorf("ffmpeg command failed: %w\n%s", err, stderrOutput.String())
-	}
-
-	// 发送完成进度
-	if f.Callback != nil {
-		f.Callback(&Progress{
-			Percentage: 100,
-			Status:     "completed",
-		})
-	}
-
-	// 获取生成的关键帧文件列表
-	var keyFrameFiles []string
-	files, err := os.ReadDir(params.OutputDir)
+	// 记录运行前已存在的文件，取消时只清理本次生成的图片
+	ext := params.ext()
+	existing, err := listOutputFiles(params.OutputDir, params.OutputPrefix, ext)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, file := range files {
-		if !file.IsDir() && strings.HasPrefix(file.Name(), params.OutputPrefix) && strings.HasSuffix(file.Name(), ".jpg") {
-			keyFrameFiles = append(keyFrameFiles, fmt.Sprintf("%s/%s", params.OutputDir, file.Name()))
+	// 构建输出文件名模式
+	outputPattern := fmt.Sprintf("%s/%s%%06d%s", params.OutputDir, params.OutputPrefix, ext)
+
+	// showinfo输出的时间相对于裁剪范围的开始时间
+	parser := &frameInfoParser{offset: trim.start}
+	err = f.run(ctx, &runOptions{
+		binary:   f.FFmpegPath,
+		args:     append(keyFrameArgs(params, length), outputPattern),
+		stdin:    params.Input,
+		stderr:   parser.parseLine,
+		progress: true,
+		total:    f.rangeDuration(ctx, params.InputPath, trim),
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			removeNewOutputFiles(params.OutputDir, params.OutputPrefix, ext, existing)
 		}
+		return nil, err
 	}
 
-	return keyFrameFiles, nil
+	// 返回的帧由showinfo输出的帧数决定，之前运行留下的编号更大的图片不会出现在结果中
+	return frameFiles(parser.frames, params.OutputDir, params.OutputPrefix, ext, params.frameLimit()), nil
 }
 
 // GetVideoDuration 获取视频文件的时长
@@ -652,43 +563,29 @@ func (f *FFmpeg) ExtractKeyFrames(params *ExtractKeyFramesParams) ([]string, err
 //
 //	duration, err := ffmpeg.GetVideoDuration("input.mp4")
 func (f *FFmpeg) GetVideoDuration(inputPath string) (int64, error) {
-	// 设置环境变量指定ffmpeg路径
-	origFfmpegPath := os.Getenv("FFMPEG_PATH")
-	os.Setenv("FFMPEG_PATH", f.FFmpegPath)
-	defer os.Setenv("FFMPEG_PATH", origFfmpegPath)
+	return f.GetVideoDurationContext(context.Background(), inputPath)
+}
 
-	// 使用ffmpeg-go获取视频信息
-	probeOutput, err := ffmpeg_go.Probe(inputPath, ffmpeg_go.KwArgs{"show_entries": "format=duration"})
+// GetVideoDurationContext 获取视频文件的时长，支持通过ctx取消或设置超时
+// 参数:
+//
+//	ctx: 控制命令生命周期的上下文
+//	inputPath: 输入视频文件路径
+//
+// 返回值:
+//
+//	int64: 视频时长，单位为毫秒
+//	error: 如果获取失败，返回错误信息；取消或超时时包装了ctx.Err()
+func (f *FFmpeg) GetVideoDurationContext(ctx context.Context, inputPath string) (int64, error) {
+	info, err := f.Probe(ctx, inputPath)
 	if err != nil {
 		return 0, err
 	}
 
-	// 解析JSON输出获取时长
-	var probeResult map[string]interface{}
-	if err := json.Unmarshal([]byte(probeOutput), &probeResult); err != nil {
-		return 0, fmt.Errorf("failed to parse probe output: %w", err)
-	}
-
-	// 打印输出JSON数据
-	fmt.Printf("Probe Output JSON: %+v\n", probeResult)
-
-	// 从map中获取时长
-	format, ok := probeResult["format"].(map[string]interface{})
-	if !ok {
-		return 0, fmt.Errorf("failed to get format from probe output")
-	}
-
-	duration, ok := format["duration"].(string)
-	if !ok || duration == "" {
+	if info.Format.Duration <= 0 {
 		return 0, fmt.Errorf("failed to get duration from probe output")
 	}
 
-	// 转换时长字符串为秒数（浮点数）
-	durationSeconds, err := strconv.ParseFloat(duration, 64)
-	if err != nil {
-		return 0, err
-	}
-
 	// 转换为毫秒并返回
-	return int64(durationSeconds * 1000), nil
+	return info.Format.Duration.Milliseconds(), nil
 }
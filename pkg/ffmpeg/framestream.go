@@ -0,0 +1,163 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"time"
+)
+
+// rgbaBytesPerPixel rawvideo输出的rgba像素格式每个像素的字节数
+const rgbaBytesPerPixel = 4
+
+// frameInfoBuffer 等待读取像素数据的帧信息的缓冲数量
+const frameInfoBuffer = 16
+
+// keyFrames 转换为选帧方式相同的ExtractKeyFramesParams，复用其校验和参数构建
+func (p *StreamFramesParams) keyFrames() *ExtractKeyFramesParams {
+	return &ExtractKeyFramesParams{
+		InputPath:      p.InputPath,
+		Input:          p.Input,
+		Mode:           p.Mode,
+		FrameInterval:  p.FrameInterval,
+		SceneThreshold: p.SceneThreshold,
+		Count:          p.Count,
+		MaxWidth:       p.MaxWidth,
+		MaxHeight:      p.MaxHeight,
+		Crop:           p.Crop,
+		TimeRange:      p.TimeRange,
+	}
+}
+
+// Validate 校验流式提取帧参数
+// 返回值:
+//
+//	error: 参数无效时返回包装了ErrInvalidParams的错误
+func (p *StreamFramesParams) Validate() error {
+	return p.keyFrames().validateFrames()
+}
+
+// streamFramesArgs 构建流式提取帧的完整参数，帧以rgba原始像素写入stdout
+func streamFramesArgs(p *StreamFramesParams, length time.Duration) []string {
+	args := frameSelectArgs(p.keyFrames(), length)
+	return append(args, "-an", "-sn", "-dn",
+		"-c:v", "rawvideo", "-pix_fmt", "rgba", "-f", "rawvideo", "pipe:1")
+}
+
+// readFrames 按showinfo输出的帧信息从r中逐帧读取像素数据并交给handler
+// infos关闭后返回；r在帧的边界处结束时说明剩余的帧没有输出（如达到帧数上限），不视为错误
+func readFrames(r io.Reader, infos <-chan Frame, handler FrameHandler) error {
+	for info := range infos {
+		if info.Width <= 0 || info.Height <= 0 {
+			return fmt.Errorf("invalid frame size %dx%d at %v", info.Width, info.Height, info.PTS)
+		}
+		stride := info.Width * rgbaBytesPerPixel
+		pix := make([]byte, stride*info.Height)
+		if _, err := io.ReadFull(r, pix); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to read frame at %v: %w", info.PTS, err)
+		}
+
+		frame := &FrameImage{
+			Frame: info,
+			Image: &image.RGBA{Pix: pix, Stride: stride, Rect: image.Rect(0, 0, info.Width, info.Height)},
+		}
+		if err := handler(frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StreamFrames 从视频文件中提取帧并逐帧交给handler，不写入磁盘
+// 选帧、裁剪和缩放与ExtractKeyFrames相同；ffmpeg以rawvideo格式通过stdout输出rgba像素，
+// 每一帧的尺寸和时间来自showinfo过滤器的输出
+// handler返回错误时终止ffmpeg并返回该错误；ctx被取消或超时时返回的错误包装了context.Canceled或context.DeadlineExceeded
+// 参数:
+//
+//	ctx: 控制命令生命周期的上下文
+//	params: 流式提取帧的参数配置
+//	handler: 接收每一帧的回调函数，在独立的goroutine中按时间顺序调用
+//
+// 返回值:
+//
+//	error: 如果提取失败或handler返回错误，返回错误信息
+//
+// 示例:
+//
+//	err := ffmpeg.StreamFrames(ctx, &ffmpeg.StreamFramesParams{
+//	    InputPath:     "input.mp4",
+//	    FrameInterval: 1,
+//	    MaxWidth:      224,
+//	    MaxHeight:     224,
+//	}, func(frame *ffmpeg.FrameImage) error {
+//	    return model.Predict(frame.PTS, frame.Image)
+//	})
+func (f *FFmpeg) StreamFrames(ctx context.Context, params *StreamFramesParams, handler FrameHandler) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+	if handler == nil {
+		return fmt.Errorf("%w: frame handler is required", ErrInvalidParams)
+	}
+	keyFrames := params.keyFrames()
+	trim := keyFrames.timeRange()
+
+	// 均匀采样指定帧数时需要先获取输入文件时长
+	length, err := f.keyFrameLength(ctx, keyFrames)
+	if err != nil {
+		return err
+	}
+
+	// handler返回错误时通过取消ctx终止ffmpeg
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// showinfo的输出先于对应帧的像素数据，读取像素前等待帧信息
+	infos := make(chan Frame, frameInfoBuffer)
+	stop := make(chan struct{})
+	parser := &frameInfoParser{
+		offset: trim.start,
+		onFrame: func(frame Frame) {
+			select {
+			case infos <- frame:
+			case <-stop:
+			}
+		},
+	}
+
+	reader, writer := io.Pipe()
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(stop)
+		err := readFrames(reader, infos, handler)
+		if err != nil {
+			reader.CloseWithError(err)
+			cancel()
+		}
+		readErr <- err
+	}()
+
+	err = f.run(ctx, &runOptions{
+		binary:   f.FFmpegPath,
+		args:     streamFramesArgs(params, length),
+		stdin:    params.Input,
+		stdout:   writer,
+		stderr:   parser.parseLine,
+		progress: true,
+		total:    f.rangeDuration(ctx, params.InputPath, trim),
+	})
+	// run返回后stderr已处理完毕，不会再有新的帧信息
+	close(infos)
+	writer.CloseWithError(err)
+
+	// 读取失败只是ffmpeg出错的结果时返回ffmpeg的错误；handler的错误是终止ffmpeg的原因，优先返回
+	if readErr := <-readErr; readErr != nil && !errors.Is(readErr, err) {
+		return readErr
+	}
+	return err
+}
@@ -0,0 +1,132 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"image"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStreamFramesArgs 测试流式提取以rawvideo格式输出到stdout
+func TestStreamFramesArgs(t *testing.T) {
+	params := &StreamFramesParams{InputPath: "in.mp4", FrameInterval: 2, MaxWidth: 224, TimeRange: TimeRange{Start: 10 * time.Second}}
+	want := []string{
+		"-ss", "10", "-i", "in.mp4",
+		"-vf", "fps=1/2,scale='min(iw,224)':-1,showinfo", "-vsync", "vfr",
+		"-an", "-sn", "-dn", "-c:v", "rawvideo", "-pix_fmt", "rgba", "-f", "rawvideo", "pipe:1",
+	}
+	if got := streamFramesArgs(params, 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("streamFramesArgs() = %q, want %q", got, want)
+	}
+}
+
+// TestStreamFramesParamsValidate 测试流式提取不需要输出目录，但仍校验选帧参数
+func TestStreamFramesParamsValidate(t *testing.T) {
+	if err := (&StreamFramesParams{InputPath: "in.mp4", FrameInterval: 1}).Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+	if err := (&StreamFramesParams{InputPath: "in.mp4"}).Validate(); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("Validate() error = %v, want ErrInvalidParams", err)
+	}
+	err := (&FFmpeg{}).StreamFrames(context.Background(), &StreamFramesParams{InputPath: "in.mp4", FrameInterval: 1}, nil)
+	if !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("StreamFrames() without handler error = %v, want ErrInvalidParams", err)
+	}
+}
+
+// fakeStreamFFmpeg 输出三帧2x1的showinfo信息，但只向stdout写出前两帧的像素
+const fakeStreamFFmpeg = `echo "[Parsed_showinfo_1 @ 0x1] n:   0 pts:      0 pts_time:0   s:2x1 iskey:1 type:I" >&2
+echo "[Parsed_showinfo_1 @ 0x1] n:   1 pts:  90000 pts_time:1   s:2x1 iskey:0 type:P" >&2
+echo "[Parsed_showinfo_1 @ 0x1] n:   2 pts: 180000 pts_time:2   s:2x1 iskey:0 type:P" >&2
+printf '\001\002\003\377\004\005\006\377'
+printf '\007\010\011\377\012\013\014\377'
+`
+
+// TestStreamFrames 测试逐帧返回像素数据及帧信息
+func TestStreamFrames(t *testing.T) {
+	ffmpeg, err := NewFFmpegWithPath(writeFakeBinary(t, "ffmpeg", fakeStreamFFmpeg), "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	var frames []*FrameImage
+	err = ffmpeg.StreamFrames(context.Background(), &StreamFramesParams{
+		InputPath:     "input.mp4",
+		FrameInterval: 1,
+		TimeRange:     TimeRange{Start: 5 * time.Second},
+	}, func(frame *FrameImage) error {
+		frames = append(frames, frame)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamFrames failed: %v", err)
+	}
+
+	if len(frames) != 2 {
+		t.Fatalf("Expected 2 frames, got %d", len(frames))
+	}
+	wantInfo := []Frame{
+		{PTS: 5 * time.Second, FrameType: "I", Width: 2, Height: 1},
+		{PTS: 6 * time.Second, FrameType: "P", Width: 2, Height: 1},
+	}
+	wantPix := [][]byte{
+		{1, 2, 3, 255, 4, 5, 6, 255},
+		{7, 8, 9, 255, 10, 11, 12, 255},
+	}
+	for i, frame := range frames {
+		if !reflect.DeepEqual(frame.Frame, wantInfo[i]) {
+			t.Errorf("frame %d info = %+v, want %+v", i, frame.Frame, wantInfo[i])
+		}
+		if !reflect.DeepEqual(frame.Image.Pix, wantPix[i]) {
+			t.Errorf("frame %d pixels = %v, want %v", i, frame.Image.Pix, wantPix[i])
+		}
+		if frame.Image.Bounds() != image.Rect(0, 0, 2, 1) {
+			t.Errorf("frame %d bounds = %v, want 2x1", i, frame.Image.Bounds())
+		}
+	}
+}
+
+// TestStreamFramesHandlerError 测试handler返回错误时停止提取并返回该错误
+func TestStreamFramesHandlerError(t *testing.T) {
+	ffmpeg, err := NewFFmpegWithPath(writeFakeBinary(t, "ffmpeg", fakeStreamFFmpeg+"sleep 10\n"), "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+	start := time.Now()
+	err = ffmpeg.StreamFrames(context.Background(), &StreamFramesParams{InputPath: "input.mp4", FrameInterval: 1}, func(*FrameImage) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("StreamFrames() error = %v, want handler error", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected handler to be called once, got %d", calls)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected ffmpeg to be terminated after handler error, took %v", elapsed)
+	}
+}
+
+// TestStreamFramesTruncated 测试像素数据不完整时返回错误
+func TestStreamFramesTruncated(t *testing.T) {
+	ffmpeg, err := NewFFmpegWithPath(writeFakeBinary(t, "ffmpeg", `echo "[Parsed_showinfo_1 @ 0x1] n:   0 pts:      0 pts_time:0   s:2x1 type:I" >&2
+printf '\001\002\003'
+`), "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	err = ffmpeg.StreamFrames(context.Background(), &StreamFramesParams{InputPath: "input.mp4", FrameInterval: 1}, func(*FrameImage) error {
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to read frame") {
+		t.Errorf("StreamFrames() error = %v, want truncated frame error", err)
+	}
+}
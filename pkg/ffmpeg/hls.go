@@ -0,0 +1,424 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHLSSegmentTime  = 6   // 默认分段时长 (秒)
+	defaultHLSAudioBitrate = 128 // 默认音频码率 (kbit/s)
+
+	hlsMasterName   = "master.m3u8"   // 主播放列表文件名
+	hlsPlaylistName = "playlist.m3u8" // 每个档位的媒体播放列表文件名
+	hlsInitName     = "init_%v.mp4"   // fMP4初始化分段文件名，%v替换为档位名称
+)
+
+// hlsMaxRateFactor 和 hlsBufSizeFactor 相对于目标码率的峰值码率和码率控制缓冲区大小，
+// 限制码率波动，让主播放列表中声明的BANDWIDTH接近实际峰值
+const (
+	hlsMaxRateFactor = 1.07
+	hlsBufSizeFactor = 1.5
+)
+
+// hlsSegmentSpec 描述一种分段格式对应的hls muxer分段类型和文件扩展名
+type hlsSegmentSpec struct {
+	segmentType string
+	ext         string
+}
+
+// hlsSegmentSpecs 支持的HLS分段格式
+var hlsSegmentSpecs = map[HLSSegmentType]hlsSegmentSpec{
+	HLSSegmentTS:   {segmentType: "mpegts", ext: ".ts"},
+	HLSSegmentFMP4: {segmentType: "fmp4", ext: ".m4s"},
+}
+
+// hlsNamePattern 档位名称允许的字符，名称用作目录名并写入-var_stream_map
+var hlsNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+var (
+	// hlsBandwidthPattern 匹配主播放列表中EXT-X-STREAM-INF的BANDWIDTH属性
+	hlsBandwidthPattern = regexp.MustCompile(`[:,]BANDWIDTH=(\d+)`)
+	// hlsResolutionPattern 匹配主播放列表中EXT-X-STREAM-INF的RESOLUTION属性
+	hlsResolutionPattern = regexp.MustCompile(`[:,]RESOLUTION=(\d+)x(\d+)`)
+	// hlsMapURIPattern 匹配媒体播放列表中EXT-X-MAP的URI属性
+	hlsMapURIPattern = regexp.MustCompile(`URI="([^"]*)"`)
+)
+
+// name 返回档位实际使用的名称
+func (r *HLSRendition) name() string {
+	if r.Name == "" && r.Height > 0 {
+		return fmt.Sprintf("%dp", r.Height)
+	}
+	return r.Name
+}
+
+// audioBitrate 返回档位实际使用的音频码率
+func (r *HLSRendition) audioBitrate() int {
+	if r.AudioBitrate == 0 {
+		return defaultHLSAudioBitrate
+	}
+	return r.AudioBitrate
+}
+
+// segmentTime 返回实际使用的分段时长
+func (p *HLSParams) segmentTime() int {
+	if p.SegmentTime == 0 {
+		return defaultHLSSegmentTime
+	}
+	return p.SegmentTime
+}
+
+// segmentType 返回实际使用的分段格式
+func (p *HLSParams) segmentType() HLSSegmentType {
+	if p.SegmentType == "" {
+		return HLSSegmentTS
+	}
+	return p.SegmentType
+}
+
+// initName 返回传给hls muxer的fMP4初始化分段文件名
+// hls muxer只在有多个档位时替换初始化分段文件名中的%v（没有%v时追加"_<档位序号>"），
+// 只有一个档位时原样使用，因此直接使用该档位的名称
+func (p *HLSParams) initName() string {
+	if len(p.Renditions) == 1 {
+		return strings.ReplaceAll(hlsInitName, "%v", p.Renditions[0].name())
+	}
+	return hlsInitName
+}
+
+// Validate 校验HLS打包参数
+// 返回值:
+//
+//	error: 参数无效时返回包装了ErrInvalidParams的错误
+func (p *HLSParams) Validate() error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidParams, fmt.Sprintf(format, args...))
+	}
+
+	if p.InputPath == "" {
+		return invalid("input path is required")
+	}
+	if p.OutputDir == "" {
+		return invalid("output directory is required")
+	}
+	if len(p.Renditions) == 0 {
+		return invalid("at least one rendition is required")
+	}
+	names := make(map[string]bool, len(p.Renditions))
+	for i := range p.Renditions {
+		r := &p.Renditions[i]
+		name := r.name()
+		if name == "" {
+			return invalid("rendition %d requires a name or height", i)
+		}
+		if !hlsNamePattern.MatchString(name) {
+			return invalid("rendition name %q may only contain letters, digits, '_' and '-'", name)
+		}
+		if names[name] {
+			return invalid("duplicate rendition name %q", name)
+		}
+		names[name] = true
+		if r.Width < 0 || r.Height < 0 {
+			return invalid("rendition %q width and height must not be negative", name)
+		}
+		if r.VideoBitrate <= 0 {
+			return invalid("rendition %q video bitrate must be positive", name)
+		}
+		if r.AudioBitrate < 0 {
+			return invalid("rendition %q audio bitrate must not be negative", name)
+		}
+	}
+	if p.SegmentTime < 0 {
+		return invalid("segment time must not be negative")
+	}
+	if _, ok := hlsSegmentSpecs[p.segmentType()]; !ok {
+		return invalid("unsupported segment type %q", p.SegmentType)
+	}
+	if _, ok := presetCPUUsed[p.Preset]; p.Preset != "" && !ok {
+		return invalid("unsupported preset %q", p.Preset)
+	}
+	return p.timeRange().validate()
+}
+
+// hlsArgs 构建HLS打包的完整参数
+// 视频经split复制为每个档位各自缩放，所有档位使用相同的-force_key_frames在分段边界插入关键帧，
+// 并关闭场景切换关键帧，hls muxer即可在所有档位的相同时间点切分；hasAudio为false时只输出视频
+func hlsArgs(p *HLSParams, hasAudio bool) []string {
+	n := len(p.Renditions)
+	filters := make([]string, 0, n+1)
+	split := "[0:v]split=" + strconv.Itoa(n)
+	for i := range p.Renditions {
+		split += fmt.Sprintf("[v%d]", i)
+	}
+	filters = append(filters, split)
+	for i, r := range p.Renditions {
+		if r.Width == 0 && r.Height == 0 {
+			filters = append(filters, fmt.Sprintf("[v%d]null[out%d]", i, i))
+			continue
+		}
+		// 只设置宽高之一时另一边按比例缩放，-2保证为偶数以满足yuv420p的要求
+		width, height := "-2", "-2"
+		if r.Width > 0 {
+			width = strconv.Itoa(r.Width)
+		}
+		if r.Height > 0 {
+			height = strconv.Itoa(r.Height)
+		}
+		filters = append(filters, fmt.Sprintf("[v%d]scale=%s:%s[out%d]", i, width, height, i))
+	}
+
+	args := p.timeRange().wrapInput(p.InputPath)
+	args = append(args, "-filter_complex", strings.Join(filters, ";"))
+	streamMap := make([]string, n)
+	for i, r := range p.Renditions {
+		args = append(args, "-map", fmt.Sprintf("[out%d]", i))
+		streamMap[i] = fmt.Sprintf("v:%d,name:%s", i, r.name())
+		if hasAudio {
+			args = append(args, "-map", "0:a:0")
+			streamMap[i] = fmt.Sprintf("v:%d,a:%d,name:%s", i, i, r.name())
+		}
+	}
+
+	args = append(args, "-c:v", videoEncoders[VideoCodecH264])
+	if p.Preset != "" {
+		args = append(args, "-preset", p.Preset)
+	}
+	for i, r := range p.Renditions {
+		args = append(args,
+			fmt.Sprintf("-b:v:%d", i), fmt.Sprintf("%dk", r.VideoBitrate),
+			fmt.Sprintf("-maxrate:v:%d", i), fmt.Sprintf("%dk", int(float64(r.VideoBitrate)*hlsMaxRateFactor)),
+			fmt.Sprintf("-bufsize:v:%d", i), fmt.Sprintf("%dk", int(float64(r.VideoBitrate)*hlsBufSizeFactor)))
+	}
+	segmentTime := strconv.Itoa(p.segmentTime())
+	args = append(args,
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%s)", segmentTime),
+		"-sc_threshold", "0")
+	if hasAudio {
+		args = append(args, "-c:a", audioEncoders[AudioCodecAAC])
+		for i, r := range p.Renditions {
+			args = append(args, fmt.Sprintf("-b:a:%d", i), fmt.Sprintf("%dk", r.audioBitrate()))
+		}
+	}
+
+	spec := hlsSegmentSpecs[p.segmentType()]
+	args = append(args,
+		"-f", "hls",
+		"-hls_time", segmentTime,
+		"-hls_playlist_type", "vod",
+		"-hls_flags", "independent_segments",
+		"-hls_segment_type", spec.segmentType)
+	if p.segmentType() == HLSSegmentFMP4 {
+		args = append(args, "-hls_fmp4_init_filename", p.initName())
+	}
+	// 输出路径的目录中包含%v时，每个档位写入各自的子目录，主播放列表写入OutputDir
+	return append(args,
+		"-hls_segment_filename", filepath.Join(p.OutputDir, "%v", "segment_%05d"+spec.ext),
+		"-master_pl_name", hlsMasterName,
+		"-var_stream_map", strings.Join(streamMap, " "),
+		filepath.Join(p.OutputDir, "%v", hlsPlaylistName))
+}
+
+// readHLSMaster 解析主播放列表，返回每个媒体播放列表路径对应的EXT-X-STREAM-INF属性
+func readHLSMaster(path string) (map[string]HLSVariant, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master playlist: %w", err)
+	}
+	defer file.Close()
+
+	dir := filepath.Dir(path)
+	variants := make(map[string]HLSVariant)
+	var pending *HLSVariant
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			pending = &HLSVariant{}
+			if match := hlsBandwidthPattern.FindStringSubmatch(line); match != nil {
+				pending.Bandwidth, _ = strconv.Atoi(match[1])
+			}
+			if match := hlsResolutionPattern.FindStringSubmatch(line); match != nil {
+				pending.Width, _ = strconv.Atoi(match[1])
+				pending.Height, _ = strconv.Atoi(match[2])
+			}
+		case line == "" || strings.HasPrefix(line, "#"):
+		case pending != nil:
+			// EXT-X-STREAM-INF之后的第一个URI行为该档位的媒体播放列表
+			variants[filepath.Join(dir, line)] = *pending
+			pending = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read master playlist: %w", err)
+	}
+	return variants, nil
+}
+
+// readHLSPlaylist 解析媒体播放列表，返回初始化分段和分段列表；offset为裁剪范围的开始时间
+func readHLSPlaylist(path string, offset time.Duration) (string, []HLSSegment, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read media playlist: %w", err)
+	}
+	defer file.Close()
+
+	dir := filepath.Dir(path)
+	var initSegment string
+	var segments []HLSSegment
+	var duration time.Duration
+	start := offset
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			if match := hlsMapURIPattern.FindStringSubmatch(line); match != nil {
+				initSegment = filepath.Join(dir, match[1])
+			}
+		case strings.HasPrefix(line, "#EXTINF:"):
+			// 格式为"#EXTINF:时长,[标题]"
+			value, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to parse media playlist: invalid segment duration %q", value)
+			}
+			duration = time.Duration(seconds * float64(time.Second))
+		case line == "" || strings.HasPrefix(line, "#"):
+		default:
+			segment := HLSSegment{
+				Path:     filepath.Join(dir, line),
+				Start:    start,
+				Duration: duration,
+			}
+			if stat, err := os.Stat(segment.Path); err == nil {
+				segment.Size = stat.Size()
+			}
+			segments = append(segments, segment)
+			start += duration
+			duration = 0
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, fmt.Errorf("failed to read media playlist: %w", err)
+	}
+	return initSegment, segments, nil
+}
+
+// readHLSManifest 根据主播放列表和每个档位的媒体播放列表构建打包结果
+func readHLSManifest(p *HLSParams) (*HLSManifest, error) {
+	masterPath := filepath.Join(p.OutputDir, hlsMasterName)
+	master, err := readHLSMaster(masterPath)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &HLSManifest{
+		MasterPlaylist: masterPath,
+		Files:          []string{masterPath},
+	}
+	for i := range p.Renditions {
+		name := p.Renditions[i].name()
+		playlist := filepath.Join(p.OutputDir, name, hlsPlaylistName)
+		variant := master[playlist]
+		variant.Name = name
+		variant.Playlist = playlist
+		variant.InitSegment, variant.Segments, err = readHLSPlaylist(playlist, p.Start)
+		if err != nil {
+			return nil, err
+		}
+
+		manifest.Files = append(manifest.Files, playlist)
+		if variant.InitSegment != "" {
+			manifest.Files = append(manifest.Files, variant.InitSegment)
+		}
+		for _, segment := range variant.Segments {
+			manifest.Files = append(manifest.Files, segment.Path)
+		}
+		manifest.Variants = append(manifest.Variants, variant)
+	}
+	return manifest, nil
+}
+
+// PackageHLS 把输入视频按码率阶梯编码并打包为HLS
+// 输出主播放列表、每个档位的媒体播放列表以及MPEG-TS或fMP4分段，各档位的关键帧和分段边界对齐；
+// 视频使用H.264编码，音频使用AAC编码，输入没有音频流时只输出视频；
+// ctx被取消或超时时会删除本次创建的档位目录和主播放列表
+// 参数:
+//
+//	ctx: 控制命令生命周期的上下文
+//	params: HLS打包参数配置
+//
+// 返回值:
+//
+//	*HLSManifest: 本次写入的所有播放列表和分段
+//	error: 如果打包失败，返回错误信息；输入没有视频流时返回包装了ErrStreamNotFound的错误
+//
+// 示例:
+//
+//	manifest, err := ffmpeg.PackageHLS(ctx, &ffmpeg.HLSParams{
+//	    InputPath: "input.mp4",
+//	    OutputDir: "/tmp/hls",
+//	    Renditions: []ffmpeg.HLSRendition{
+//	        {Height: 1080, VideoBitrate: 5000},
+//	        {Height: 720, VideoBitrate: 2800},
+//	        {Height: 480, VideoBitrate: 1400, AudioBitrate: 96},
+//	    },
+//	    SegmentType: ffmpeg.HLSSegmentFMP4,
+//	})
+func (f *FFmpeg) PackageHLS(ctx context.Context, params *HLSParams) (*HLSManifest, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	info, err := f.Probe(ctx, params.InputPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(info.VideoStreams()) == 0 {
+		return nil, fmt.Errorf("%w: no video stream in %s", ErrStreamNotFound, params.InputPath)
+	}
+
+	// 提前创建每个档位的目录，并记录本次新建的目录，取消时只清理这些目录
+	masterPath := filepath.Join(params.OutputDir, hlsMasterName)
+	_, statErr := os.Stat(masterPath)
+	masterExisted := statErr == nil
+	var created []string
+	for i := range params.Renditions {
+		dir := filepath.Join(params.OutputDir, params.Renditions[i].name())
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			created = append(created, dir)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	err = f.run(ctx, &runOptions{
+		binary:   f.FFmpegPath,
+		args:     hlsArgs(params, len(info.AudioStreams()) > 0),
+		progress: true,
+		total:    params.timeRange().within(info.Format.Duration),
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			for _, dir := range created {
+				os.RemoveAll(dir)
+			}
+			if !masterExisted {
+				os.Remove(masterPath)
+			}
+		}
+		return nil, err
+	}
+
+	return readHLSManifest(params)
+}
@@ -0,0 +1,249 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHLSArgs 测试码率阶梯和分段格式生成的参数
+func TestHLSArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   HLSParams
+		hasAudio bool
+		want     []string
+	}{
+		{
+			name: "ts ladder with audio",
+			params: HLSParams{
+				InputPath: "in.mp4",
+				OutputDir: "out",
+				Renditions: []HLSRendition{
+					{Height: 720, VideoBitrate: 2800},
+					{Name: "low", Width: 640, VideoBitrate: 800, AudioBitrate: 64},
+				},
+				Preset: "veryfast",
+			},
+			hasAudio: true,
+			want: []string{
+				"-i", "in.mp4",
+				"-filter_complex", "[0:v]split=2[v0][v1];[v0]scale=-2:720[out0];[v1]scale=640:-2[out1]",
+				"-map", "[out0]", "-map", "0:a:0", "-map", "[out1]", "-map", "0:a:0",
+				"-c:v", "libx264", "-preset", "veryfast",
+				"-b:v:0", "2800k", "-maxrate:v:0", "2996k", "-bufsize:v:0", "4200k",
+				"-b:v:1", "800k", "-maxrate:v:1", "856k", "-bufsize:v:1", "1200k",
+				"-force_key_frames", "expr:gte(t,n_forced*6)", "-sc_threshold", "0",
+				"-c:a", "aac", "-b:a:0", "128k", "-b:a:1", "64k",
+				"-f", "hls", "-hls_time", "6", "-hls_playlist_type", "vod", "-hls_flags", "independent_segments",
+				"-hls_segment_type", "mpegts",
+				"-hls_segment_filename", filepath.Join("out", "%v", "segment_%05d.ts"),
+				"-master_pl_name", "master.m3u8",
+				"-var_stream_map", "v:0,a:0,name:720p v:1,a:1,name:low",
+				filepath.Join("out", "%v", "playlist.m3u8"),
+			},
+		},
+		{
+			name: "fmp4 without audio",
+			params: HLSParams{
+				InputPath:   "in.mp4",
+				OutputDir:   "out",
+				Renditions:  []HLSRendition{{Name: "source", VideoBitrate: 4000}},
+				SegmentTime: 4,
+				SegmentType: HLSSegmentFMP4,
+				TimeRange:   TimeRange{Start: 10 * time.Second},
+			},
+			want: []string{
+				"-ss", "10", "-i", "in.mp4",
+				"-filter_complex", "[0:v]split=1[v0];[v0]null[out0]",
+				"-map", "[out0]",
+				"-c:v", "libx264",
+				"-b:v:0", "4000k", "-maxrate:v:0", "4280k", "-bufsize:v:0", "6000k",
+				"-force_key_frames", "expr:gte(t,n_forced*4)", "-sc_threshold", "0",
+				"-f", "hls", "-hls_time", "4", "-hls_playlist_type", "vod", "-hls_flags", "independent_segments",
+				"-hls_segment_type", "fmp4", "-hls_fmp4_init_filename", "init_source.mp4",
+				"-hls_segment_filename", filepath.Join("out", "%v", "segment_%05d.m4s"),
+				"-master_pl_name", "master.m3u8",
+				"-var_stream_map", "v:0,name:source",
+				filepath.Join("out", "%v", "playlist.m3u8"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hlsArgs(&tt.params, tt.hasAudio); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hlsArgs() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHLSParamsValidate 测试无效的HLS打包参数被拒绝
+func TestHLSParamsValidate(t *testing.T) {
+	ladder := []HLSRendition{{Height: 720, VideoBitrate: 2800}}
+	tests := []struct {
+		name   string
+		params HLSParams
+	}{
+		{"missing output dir", HLSParams{InputPath: "in.mp4", Renditions: ladder}},
+		{"empty ladder", HLSParams{InputPath: "in.mp4", OutputDir: "out"}},
+		{"missing bitrate", HLSParams{InputPath: "in.mp4", OutputDir: "out", Renditions: []HLSRendition{{Height: 720}}}},
+		{"missing name", HLSParams{InputPath: "in.mp4", OutputDir: "out", Renditions: []HLSRendition{{Width: 1280, VideoBitrate: 2800}}}},
+		{"invalid name", HLSParams{InputPath: "in.mp4", OutputDir: "out", Renditions: []HLSRendition{{Name: "hd 720", VideoBitrate: 2800}}}},
+		{"duplicate name", HLSParams{InputPath: "in.mp4", OutputDir: "out", Renditions: []HLSRendition{{Height: 720, VideoBitrate: 2800}, {Name: "720p", VideoBitrate: 1400}}}},
+		{"unknown segment type", HLSParams{InputPath: "in.mp4", OutputDir: "out", Renditions: ladder, SegmentType: "mp4"}},
+		{"unknown preset", HLSParams{InputPath: "in.mp4", OutputDir: "out", Renditions: ladder, Preset: "turbo"}},
+		{"invalid time range", HLSParams{InputPath: "in.mp4", OutputDir: "out", Renditions: ladder, TimeRange: TimeRange{End: time.Second, Duration: time.Second}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(); !errors.Is(err, ErrInvalidParams) {
+				t.Errorf("Validate() error = %v, want ErrInvalidParams", err)
+			}
+		})
+	}
+}
+
+// fakeHLSFFmpeg 模拟hls muxer写出主播放列表和每个fMP4档位，档位名称取自-var_stream_map
+// 与hls muxer一样，只有多个档位时才替换初始化分段文件名中的%v，没有%v时在文件名后追加"_<档位序号>"
+const fakeHLSFFmpeg = `prev=""
+for arg; do
+	[ "$prev" = "-hls_fmp4_init_filename" ] && init="$arg"
+	[ "$prev" = "-var_stream_map" ] && names=$(echo "$arg" | tr ' ' '\n' | sed 's/.*name://')
+	prev="$arg"
+done
+count=$(echo "$names" | wc -l)
+echo "#EXTM3U" > master.m3u8
+index=0
+for name in $names; do
+	case $name in
+	720p) echo '#EXT-X-STREAM-INF:BANDWIDTH=3130400,RESOLUTION=1280x720' >> master.m3u8 ;;
+	*) echo '#EXT-X-STREAM-INF:BANDWIDTH=1056000,RESOLUTION=640x360' >> master.m3u8 ;;
+	esac
+	echo "$name/playlist.m3u8" >> master.m3u8
+
+	init_name="$init"
+	if [ "$count" -gt 1 ]; then
+		case "$init" in
+		*%v*) init_name=$(echo "$init" | sed "s/%v/$name/") ;;
+		*) init_name="${init%.mp4}_$index.mp4" ;;
+		esac
+	fi
+	index=$((index + 1))
+	echo init > "$name/$init_name"
+	echo aaaa > $name/segment_00000.m4s
+	echo bb > $name/segment_00001.m4s
+	cat > $name/playlist.m3u8 <<EOF
+#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:6
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-MAP:URI="$init_name"
+#EXTINF:6.000000,
+segment_00000.m4s
+#EXTINF:2.500000,
+segment_00001.m4s
+#EXT-X-ENDLIST
+EOF
+done
+`
+
+// TestPackageHLSManifest 测试根据ffmpeg写出的播放列表返回每个档位的文件，初始化分段以档位名称命名
+func TestPackageHLSManifest(t *testing.T) {
+	type variantInfo struct {
+		name                     string
+		width, height, bandwidth int
+	}
+	hd := variantInfo{"720p", 1280, 720, 3130400}
+	sd := variantInfo{"360p", 640, 360, 1056000}
+	tests := []struct {
+		name       string
+		renditions []HLSRendition
+		streamMap  string
+		variants   []variantInfo
+	}{
+		{
+			name:       "multiple renditions",
+			renditions: []HLSRendition{{Height: 720, VideoBitrate: 2800}, {Height: 360, VideoBitrate: 800}},
+			streamMap:  "-var_stream_map v:0,a:0,name:720p v:1,a:1,name:360p ",
+			variants:   []variantInfo{hd, sd},
+		},
+		{
+			name:       "single rendition",
+			renditions: []HLSRendition{{Height: 720, VideoBitrate: 2800}},
+			streamMap:  "-var_stream_map v:0,a:0,name:720p ",
+			variants:   []variantInfo{hd},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := t.TempDir()
+			argsFile := filepath.Join(t.TempDir(), "args")
+			fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `echo "$@" > "`+argsFile+`"
+cd "`+outputDir+`"
+`+fakeHLSFFmpeg)
+
+			ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, writeFakeFFprobe(t), nil)
+			if err != nil {
+				t.Fatalf("Failed to create FFmpeg instance: %v", err)
+			}
+
+			manifest, err := ffmpeg.PackageHLS(context.Background(), &HLSParams{
+				InputPath:   "input.mp4",
+				OutputDir:   outputDir,
+				Renditions:  tt.renditions,
+				SegmentType: HLSSegmentFMP4,
+				TimeRange:   TimeRange{Start: 100 * time.Second},
+			})
+			if err != nil {
+				t.Fatalf("PackageHLS failed: %v", err)
+			}
+
+			// 模拟的ffprobe返回音频流，每个档位都应映射音频
+			data, err := os.ReadFile(argsFile)
+			if err != nil {
+				t.Fatalf("Failed to read recorded arguments: %v", err)
+			}
+			if !strings.Contains(string(data), tt.streamMap) {
+				t.Errorf("Expected audio in every variant, got args: %s", data)
+			}
+
+			want := &HLSManifest{MasterPlaylist: filepath.Join(outputDir, "master.m3u8")}
+			want.Files = []string{want.MasterPlaylist}
+			for _, info := range tt.variants {
+				dir := filepath.Join(outputDir, info.name)
+				variant := HLSVariant{
+					Name:        info.name,
+					Playlist:    filepath.Join(dir, "playlist.m3u8"),
+					InitSegment: filepath.Join(dir, "init_"+info.name+".mp4"),
+					Segments: []HLSSegment{
+						{Path: filepath.Join(dir, "segment_00000.m4s"), Start: 100 * time.Second, Duration: 6 * time.Second, Size: 5},
+						{Path: filepath.Join(dir, "segment_00001.m4s"), Start: 106 * time.Second, Duration: 2500 * time.Millisecond, Size: 3},
+					},
+					Bandwidth: info.bandwidth,
+					Width:     info.width,
+					Height:    info.height,
+				}
+				want.Variants = append(want.Variants, variant)
+				want.Files = append(want.Files, variant.Playlist, variant.InitSegment, variant.Segments[0].Path, variant.Segments[1].Path)
+			}
+			if !reflect.DeepEqual(manifest, want) {
+				t.Errorf("PackageHLS() =\n%+v\nwant\n%+v", manifest, want)
+			}
+			for _, file := range want.Files {
+				if _, err := os.Stat(file); err != nil {
+					t.Errorf("Expected %s to be written: %v", file, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mode 返回实际使用的提取方式
+func (p *ExtractKeyFramesParams) mode() KeyFrameMode {
+	if p.Mode == "" {
+		return KeyFrameModeInterval
+	}
+	return p.Mode
+}
+
+// sceneThreshold 返回实际使用的场景切换阈值
+func (p *ExtractKeyFramesParams) sceneThreshold() float64 {
+	if p.SceneThreshold == 0 {
+		return defaultSceneThreshold
+	}
+	return p.SceneThreshold
+}
+
+// maxImageQuality Quality的最大值
+const maxImageQuality = 100
+
+// imageFormatSpec 描述一种图片格式的文件扩展名和编码器
+type imageFormatSpec struct {
+	ext     string
+	encoder string
+}
+
+// imageFormats 支持的图片格式
+var imageFormats = map[ImageFormat]imageFormatSpec{
+	ImageFormatJPEG: {ext: ".jpg", encoder: "mjpeg"},
+	ImageFormatPNG:  {ext: ".png", encoder: "png"},
+	ImageFormatWebP: {ext: ".webp", encoder: "libwebp"},
+}
+
+// format 返回实际使用的图片格式
+func (p *ExtractKeyFramesParams) format() ImageFormat {
+	if p.Format == "" {
+		return ImageFormatJPEG
+	}
+	return p.Format
+}
+
+// ext 返回输出图片的文件扩展名
+func (p *ExtractKeyFramesParams) ext() string {
+	return imageFormats[p.format()].ext
+}
+
+// Validate 校验提取关键帧参数
+// 返回值:
+//
+//	error: 参数无效时返回包装了ErrInvalidParams的错误
+func (p *ExtractKeyFramesParams) Validate() error {
+	if err := p.validateFrames(); err != nil {
+		return err
+	}
+	if p.OutputDir == "" {
+		return fmt.Errorf("%w: output directory is required", ErrInvalidParams)
+	}
+	return nil
+}
+
+// validateFrames 校验选帧及图片参数，不包括输出目录
+func (p *ExtractKeyFramesParams) validateFrames() error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidParams, fmt.Sprintf(format, args...))
+	}
+
+	if err := validateInput(p.InputPath, p.Input); err != nil {
+		return err
+	}
+	// 数据流只能读取一次，无法先获取时长
+	if p.Input != nil && p.mode() == KeyFrameModeCount {
+		return invalid("count mode requires an input path")
+	}
+	switch p.mode() {
+	case KeyFrameModeInterval:
+		if p.FrameInterval <= 0 {
+			return invalid("frame interval must be positive")
+		}
+	case KeyFrameModeIFrames:
+	case KeyFrameModeScene:
+		if p.SceneThreshold < 0 || p.SceneThreshold > 1 {
+			return invalid("scene threshold %g is out of range 0-1", p.SceneThreshold)
+		}
+	case KeyFrameModeCount:
+		if p.Count <= 0 {
+			return invalid("frame count must be positive")
+		}
+	default:
+		return invalid("unsupported key frame mode %q", p.Mode)
+	}
+	if _, ok := imageFormats[p.format()]; !ok {
+		return invalid("unsupported image format %q", p.Format)
+	}
+	if p.Quality < 0 || p.Quality > maxImageQuality {
+		return invalid("quality %d is out of range 1-%d", p.Quality, maxImageQuality)
+	}
+	if p.Quality > 0 && p.format() == ImageFormatPNG {
+		return invalid("png is lossless and does not support quality")
+	}
+	if p.MaxWidth < 0 || p.MaxHeight < 0 {
+		return invalid("max width and height must not be negative")
+	}
+	if c := p.Crop; c != nil && (c.X < 0 || c.Y < 0 || c.Width <= 0 || c.Height <= 0) {
+		return invalid("crop area %dx%d+%d+%d is invalid", c.Width, c.Height, c.X, c.Y)
+	}
+	// 提取帧总是需要解码，输入端定位已经是逐帧精确的；
+	// 输出端定位会让Start之前的帧也经过showinfo，无法与输出的图片对应
+	if p.AccurateSeek {
+		return invalid("accurate seek is not supported when extracting frames, input seeking is already frame accurate")
+	}
+	return p.timeRange().validate()
+}
+
+// keyFrameArgs 构建提取关键帧的输入、过滤器及编码参数，不包含输出文件
+// length为KeyFrameModeCount要均匀采样的时长，其他方式忽略
+func keyFrameArgs(p *ExtractKeyFramesParams, length time.Duration) []string {
+	return append(frameSelectArgs(p, length), imageEncodeArgs(p)...)
+}
+
+// frameSelectArgs 构建选帧的输入及过滤器参数，不包含编码参数
+func frameSelectArgs(p *ExtractKeyFramesParams, length time.Duration) []string {
+	trim := p.timeRange()
+	var args []string
+	if p.mode() == KeyFrameModeIFrames {
+		// 让解码器跳过所有非关键帧，无需解码整个视频
+		args = []string{"-skip_frame", "nokey"}
+	}
+	args = append(args, trim.wrapInput(inputURL(p.InputPath, p.Input))...)
+	args = append(args, "-vf", strings.Join(frameFilters(p, length), ","), "-vsync", "vfr")
+	if limit := p.frameLimit(); limit > 0 {
+		// 限制输出帧数，避免末尾多出一帧
+		args = append(args, "-frames:v", strconv.Itoa(limit))
+	}
+	return args
+}
+
+// frameLimit 返回通过-frames:v限制的输出帧数，0表示不限制
+func (p *ExtractKeyFramesParams) frameLimit() int {
+	if p.mode() == KeyFrameModeCount {
+		return p.Count
+	}
+	return 0
+}
+
+// frameFilters 构建选帧过滤器链
+// 裁剪和缩放位于选帧之后，只处理输出的帧；
+// 过滤器链末尾的showinfo输出每一帧的时间、类型和最终尺寸，与输出的帧一一对应
+func frameFilters(p *ExtractKeyFramesParams, length time.Duration) []string {
+	var filters []string
+	switch p.mode() {
+	case KeyFrameModeIFrames:
+	case KeyFrameModeScene:
+		threshold := strconv.FormatFloat(p.sceneThreshold(), 'f', -1, 64)
+		filters = []string{
+			fmt.Sprintf("select='gt(scene,%s)'", threshold),
+			"metadata=print:key=" + sceneScoreKey,
+		}
+	case KeyFrameModeCount:
+		// 以Count/length的帧率采样
+		filters = []string{fmt.Sprintf("fps=%d/%s", p.Count, formatSeconds(length))}
+	default:
+		filters = []string{fmt.Sprintf("fps=1/%d", p.FrameInterval)}
+	}
+	filters = append(filters, imageFilters(p)...)
+	return append(filters, "showinfo")
+}
+
+// imageFilters 构建裁剪和缩放过滤器
+// 缩放只缩小不放大；同时限制宽高时按比例缩小到两者之内
+func imageFilters(p *ExtractKeyFramesParams) []string {
+	var filters []string
+	if c := p.Crop; c != nil {
+		filters = append(filters, fmt.Sprintf("crop=%d:%d:%d:%d", c.Width, c.Height, c.X, c.Y))
+	}
+	if p.MaxWidth > 0 || p.MaxHeight > 0 {
+		// 只限制一边时另一边按比例缩放
+		width, height := "-1", "-1"
+		if p.MaxWidth > 0 {
+			width = fmt.Sprintf("'min(iw,%d)'", p.MaxWidth)
+		}
+		if p.MaxHeight > 0 {
+			height = fmt.Sprintf("'min(ih,%d)'", p.MaxHeight)
+		}
+		scale := fmt.Sprintf("scale=%s:%s", width, height)
+		if p.MaxWidth > 0 && p.MaxHeight > 0 {
+			scale += ":force_original_aspect_ratio=decrease"
+		}
+		filters = append(filters, scale)
+	}
+	return filters
+}
+
+// imageEncodeArgs 构建图片编码参数
+func imageEncodeArgs(p *ExtractKeyFramesParams) []string {
+	args := []string{"-c:v", imageFormats[p.format()].encoder}
+	if p.Quality == 0 {
+		return args
+	}
+	switch p.format() {
+	case ImageFormatJPEG:
+		args = append(args, "-q:v", strconv.Itoa(jpegQScale(p.Quality)))
+	case ImageFormatWebP:
+		args = append(args, "-quality", strconv.Itoa(p.Quality))
+	}
+	return args
+}
+
+// jpegQScale 将1-100的质量映射为mjpeg的-q:v，范围2-31，值越小质量越高
+func jpegQScale(quality int) int {
+	return 31 - int(math.Round(float64(quality-1)*29/(maxImageQuality-1)))
+}
+
+// sceneScoreKey select过滤器写入帧元数据的场景切换分数键名
+const sceneScoreKey = "lavfi.scene_score"
+
+var (
+	// showinfoSizePattern 匹配showinfo输出中的帧尺寸，如"s:1920x1080"
+	showinfoSizePattern = regexp.MustCompile(`\bs:(\d+)x(\d+)`)
+	// showinfoTypePattern 匹配showinfo输出中的帧类型，新版本为"type:I"，旧版本为"pict_type:I"
+	showinfoTypePattern = regexp.MustCompile(`\b(?:pict_)?type:([A-Z?])`)
+)
+
+// frameInfoParser 从metadata和showinfo过滤器的stderr输出中收集每一帧的信息
+// metadata过滤器位于showinfo之前，其输出的场景分数属于随后的showinfo帧
+type frameInfoParser struct {
+	offset     time.Duration // 裁剪范围的开始时间，加到帧时间上得到在输入文件中的时间
+	onFrame    func(Frame)   // 每解析出一帧时调用，为nil时收集到frames中
+	frames     []Frame
+	sceneScore float64 // 尚未归属的场景分数
+}
+
+// parseLine 解析一行stderr输出，返回该行是否为过滤器输出的帧信息
+func (p *frameInfoParser) parseLine(line string) bool {
+	if !filterInfoLine(line) {
+		return false
+	}
+	if strings.Contains(line, "Parsed_metadata") {
+		if i := strings.Index(line, sceneScoreKey+"="); i >= 0 {
+			if score, err := strconv.ParseFloat(strings.TrimSpace(line[i+len(sceneScoreKey)+1:]), 64); err == nil {
+				p.sceneScore = score
+			}
+		}
+		return true
+	}
+
+	pts, ok := parseShowinfoTime(line)
+	if !ok || !strings.Contains(line, " n:") {
+		return true
+	}
+	frame := Frame{
+		PTS:        p.offset + pts,
+		SceneScore: p.sceneScore,
+	}
+	if match := showinfoSizePattern.FindStringSubmatch(line); match != nil {
+		frame.Width, _ = strconv.Atoi(match[1])
+		frame.Height, _ = strconv.Atoi(match[2])
+	}
+	if match := showinfoTypePattern.FindStringSubmatch(line); match != nil {
+		frame.FrameType = match[1]
+	}
+	p.sceneScore = 0
+	if p.onFrame != nil {
+		p.onFrame(frame)
+		return true
+	}
+	p.frames = append(p.frames, frame)
+	return true
+}
+
+// filterInfoLine 判断一行stderr输出是否来自showinfo或metadata过滤器
+// 这两个过滤器为每一帧输出一行或多行日志，如帧信息和side data
+func filterInfoLine(line string) bool {
+	return strings.Contains(line, "Parsed_showinfo") || strings.Contains(line, "Parsed_metadata")
+}
+
+// frameFiles 为收集到的帧填充输出图片路径
+// image2 muxer从1开始按顺序编号，经过showinfo的每一帧都会写出一张图片；
+// limit为-frames:v限制的帧数，为0表示不限制；超出的帧经过了showinfo却没有写出，会被丢弃
+func frameFiles(frames []Frame, dir, prefix, ext string, limit int) []Frame {
+	if limit > 0 && len(frames) > limit {
+		frames = frames[:limit]
+	}
+	result := make([]Frame, 0, len(frames))
+	for i, frame := range frames {
+		frame.Path = filepath.Join(dir, fmt.Sprintf("%s%06d%s", prefix, i+1, ext))
+		result = append(result, frame)
+	}
+	return result
+}
+
+// keyFrameLength 获取KeyFrameModeCount需要均匀采样的时长
+func (f *FFmpeg) keyFrameLength(ctx context.Context, p *ExtractKeyFramesParams) (time.Duration, error) {
+	if p.mode() != KeyFrameModeCount {
+		return 0, nil
+	}
+
+	info, err := f.Probe(ctx, p.InputPath)
+	if err != nil {
+		return 0, err
+	}
+	length := p.timeRange().within(info.Format.Duration)
+	if length <= 0 {
+		return 0, fmt.Errorf("%w: time range is outside of the input duration %v", ErrInvalidParams, info.Format.Duration)
+	}
+	return length, nil
+}
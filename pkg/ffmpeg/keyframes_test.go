@@ -0,0 +1,188 @@
+package ffmpeg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestKeyFrameArgs 测试不同提取方式生成的参数
+func TestKeyFrameArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		params ExtractKeyFramesParams
+		length time.Duration
+		want   []string
+	}{
+		{
+			name:   "interval",
+			params: ExtractKeyFramesParams{InputPath: "in.mp4", FrameInterval: 5},
+			want:   []string{"-i", "in.mp4", "-vf", "fps=1/5,showinfo", "-vsync", "vfr", "-c:v", "mjpeg"},
+		},
+		{
+			name:   "iframes skip non-key frames",
+			params: ExtractKeyFramesParams{InputPath: "in.mp4", Mode: KeyFrameModeIFrames, TimeRange: TimeRange{Start: 30 * time.Second}},
+			want:   []string{"-skip_frame", "nokey", "-ss", "30", "-i", "in.mp4", "-vf", "showinfo", "-vsync", "vfr", "-c:v", "mjpeg"},
+		},
+		{
+			name:   "scene with default threshold",
+			params: ExtractKeyFramesParams{InputPath: "in.mp4", Mode: KeyFrameModeScene},
+			want:   []string{"-i", "in.mp4", "-vf", "select='gt(scene,0.4)',metadata=print:key=lavfi.scene_score,showinfo", "-vsync", "vfr", "-c:v", "mjpeg"},
+		},
+		{
+			name:   "time range",
+			params: ExtractKeyFramesParams{InputPath: "in.mp4", FrameInterval: 1, TimeRange: TimeRange{Start: 2 * time.Second, Duration: 3 * time.Second}},
+			want:   []string{"-ss", "2", "-i", "in.mp4", "-t", "3", "-vf", "fps=1/1,showinfo", "-vsync", "vfr", "-c:v", "mjpeg"},
+		},
+		{
+			name:   "evenly spaced count",
+			params: ExtractKeyFramesParams{InputPath: "in.mp4", Mode: KeyFrameModeCount, Count: 12},
+			length: 120120 * time.Millisecond,
+			want:   []string{"-i", "in.mp4", "-vf", "fps=12/120.12,showinfo", "-vsync", "vfr", "-frames:v", "12", "-c:v", "mjpeg"},
+		},
+		{
+			name:   "png thumbnail within box",
+			params: ExtractKeyFramesParams{InputPath: "in.mp4", FrameInterval: 10, Format: ImageFormatPNG, MaxWidth: 320, MaxHeight: 240},
+			want:   []string{"-i", "in.mp4", "-vf", "fps=1/10,scale='min(iw,320)':'min(ih,240)':force_original_aspect_ratio=decrease,showinfo", "-vsync", "vfr", "-c:v", "png"},
+		},
+		{
+			name:   "cropped jpeg with max width and quality",
+			params: ExtractKeyFramesParams{InputPath: "in.mp4", Mode: KeyFrameModeIFrames, Quality: 100, MaxWidth: 640, Crop: &CropRect{X: 10, Y: 20, Width: 1280, Height: 720}},
+			want:   []string{"-skip_frame", "nokey", "-i", "in.mp4", "-vf", "crop=1280:720:10:20,scale='min(iw,640)':-1,showinfo", "-vsync", "vfr", "-c:v", "mjpeg", "-q:v", "2"},
+		},
+		{
+			name:   "webp with max height and quality",
+			params: ExtractKeyFramesParams{InputPath: "in.mp4", FrameInterval: 1, Format: ImageFormatWebP, Quality: 80, MaxHeight: 180},
+			want:   []string{"-i", "in.mp4", "-vf", "fps=1/1,scale=-1:'min(ih,180)',showinfo", "-vsync", "vfr", "-c:v", "libwebp", "-quality", "80"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keyFrameArgs(&tt.params, tt.length); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keyFrameArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExtractKeyFramesParamsValidate 测试无效的提取关键帧参数被拒绝
+func TestExtractKeyFramesParamsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		params ExtractKeyFramesParams
+	}{
+		{"missing interval", ExtractKeyFramesParams{InputPath: "in.mp4", OutputDir: "out"}},
+		{"missing count", ExtractKeyFramesParams{InputPath: "in.mp4", OutputDir: "out", Mode: KeyFrameModeCount}},
+		{"threshold out of range", ExtractKeyFramesParams{InputPath: "in.mp4", OutputDir: "out", Mode: KeyFrameModeScene, SceneThreshold: 2}},
+		{"unknown mode", ExtractKeyFramesParams{InputPath: "in.mp4", OutputDir: "out", Mode: "all"}},
+		{"unknown image format", ExtractKeyFramesParams{InputPath: "in.mp4", OutputDir: "out", FrameInterval: 1, Format: "bmp"}},
+		{"quality out of range", ExtractKeyFramesParams{InputPath: "in.mp4", OutputDir: "out", FrameInterval: 1, Quality: 101}},
+		{"png quality", ExtractKeyFramesParams{InputPath: "in.mp4", OutputDir: "out", FrameInterval: 1, Format: ImageFormatPNG, Quality: 90}},
+		{"negative max width", ExtractKeyFramesParams{InputPath: "in.mp4", OutputDir: "out", FrameInterval: 1, MaxWidth: -1}},
+		{"empty crop", ExtractKeyFramesParams{InputPath: "in.mp4", OutputDir: "out", FrameInterval: 1, Crop: &CropRect{Width: 100}}},
+		{"accurate seek", ExtractKeyFramesParams{InputPath: "in.mp4", OutputDir: "out", FrameInterval: 1, TimeRange: TimeRange{Start: time.Second, AccurateSeek: true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(); !errors.Is(err, ErrInvalidParams) {
+				t.Errorf("Validate() error = %v, want ErrInvalidParams", err)
+			}
+		})
+	}
+}
+
+// TestJPEGQScale 测试1-100的质量映射到mjpeg的-q:v范围
+func TestJPEGQScale(t *testing.T) {
+	for quality, want := range map[int]int{1: 31, 50: 17, 75: 9, 100: 2} {
+		if got := jpegQScale(quality); got != want {
+			t.Errorf("jpegQScale(%d) = %d, want %d", quality, got, want)
+		}
+	}
+}
+
+// TestFrameInfoParser 测试从showinfo和metadata输出中解析帧信息
+func TestFrameInfoParser(t *testing.T) {
+	parser := &frameInfoParser{offset: 10 * time.Second}
+	lines := []string{
+		"[Parsed_metadata_1 @ 0x55d0] frame:0    pts:48      pts_time:2.002",
+		"[Parsed_metadata_1 @ 0x55d0] lavfi.scene_score=0.512300",
+		"[Parsed_showinfo_2 @ 0x55d1] n:   0 pts:     48 pts_time:2.002   duration:      1 fmt:yuv420p sar:1/1 s:1920x1080 i:P iskey:1 type:I checksum:5A3C",
+		"[Parsed_showinfo_2 @ 0x55d1] n:   1 pts:    120 pts_time:5       pos:  1024 fmt:yuv420p sar:1/1 s:1280x720 i:P iskey:0 pict_type:P",
+		"[Parsed_showinfo_2 @ 0x55d1]   side data - SEI unregistered data:",
+		"frame=    2 fps=0.0 q=-0.0 size=N/A time=00:00:05.00 bitrate=N/A",
+	}
+	for _, line := range lines {
+		parser.parseLine(line)
+	}
+
+	want := []Frame{
+		{PTS: 12002 * time.Millisecond, FrameType: "I", Width: 1920, Height: 1080, SceneScore: 0.5123},
+		{PTS: 15 * time.Second, FrameType: "P", Width: 1280, Height: 720},
+	}
+	if !reflect.DeepEqual(parser.frames, want) {
+		t.Errorf("frames =\n%+v\nwant\n%+v", parser.frames, want)
+	}
+}
+
+// TestExtractKeyFramesInfo 测试返回的帧与输出图片一一对应，并带有showinfo输出的信息
+func TestExtractKeyFramesInfo(t *testing.T) {
+	outputDir := t.TempDir()
+
+	// 之前运行留下了更多的图片，未被本次运行覆盖的图片不属于本次结果，
+	// 即使其修改时间被改变，或被覆盖的图片修改时间没有变化
+	now := time.Now().Truncate(time.Second)
+	for _, name := range []string{"frame_000001.jpg", "frame_000002.jpg", "frame_000003.jpg"} {
+		path := filepath.Join(outputDir, name)
+		if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+			t.Fatalf("Failed to write stale frame: %v", err)
+		}
+		if err := os.Chtimes(path, now, now); err != nil {
+			t.Fatalf("Failed to set stale frame time: %v", err)
+		}
+	}
+
+	// showinfo输出三帧，但帧数上限让最后一帧没有写出
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `echo "[Parsed_showinfo_1 @ 0x1] n:   0 pts:      0 pts_time:0       s:640x360 iskey:1 type:I" >&2
+echo "[Parsed_showinfo_1 @ 0x1] n:   1 pts:  90000 pts_time:1.5     s:640x360 iskey:0 type:P" >&2
+echo "[Parsed_showinfo_1 @ 0x1] n:   2 pts: 180000 pts_time:3       s:640x360 iskey:0 type:B" >&2
+echo a > "`+outputDir+`/frame_000001.jpg"
+echo b > "`+outputDir+`/frame_000002.jpg"
+touch -d @`+strconv.FormatInt(now.Unix(), 10)+` "`+outputDir+`/frame_000001.jpg" "`+outputDir+`/frame_000002.jpg"
+`)
+
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, writeFakeFFprobe(t), nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	frames, err := ffmpeg.ExtractKeyFrames(&ExtractKeyFramesParams{
+		InputPath:    "input.mp4",
+		OutputDir:    outputDir,
+		Mode:         KeyFrameModeCount,
+		Count:        2,
+		OutputPrefix: "frame_",
+		TimeRange:    TimeRange{Start: 30 * time.Second},
+	})
+	if err != nil {
+		t.Fatalf("ExtractKeyFrames failed: %v", err)
+	}
+
+	want := []Frame{
+		{Path: filepath.Join(outputDir, "frame_000001.jpg"), PTS: 30 * time.Second, FrameType: "I", Width: 640, Height: 360},
+		{Path: filepath.Join(outputDir, "frame_000002.jpg"), PTS: 31500 * time.Millisecond, FrameType: "P", Width: 640, Height: 360},
+	}
+	if !reflect.DeepEqual(frames, want) {
+		t.Errorf("ExtractKeyFrames() =\n%+v\nwant\n%+v", frames, want)
+	}
+	for _, frame := range frames {
+		if _, err := os.Stat(frame.Path); err != nil {
+			t.Errorf("Frame file %s does not exist: %v", frame.Path, err)
+		}
+	}
+}
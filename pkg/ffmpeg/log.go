@@ -0,0 +1,25 @@
+package ffmpeg
+
+import (
+	"context"
+	"log/slog"
+)
+
+// discardHandler 丢弃所有日志的slog.Handler，用于未设置Logger时保持静默
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// discardLogger 默认的静默日志记录器
+var discardLogger = slog.New(discardHandler{})
+
+// logger 返回实例的日志记录器，未设置时返回静默日志记录器
+func (f *FFmpeg) logger() *slog.Logger {
+	if f.Logger == nil {
+		return discardLogger
+	}
+	return f.Logger
+}
@@ -0,0 +1,60 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	pipeInput  = "pipe:0" // 从stdin读取输入
+	pipeOutput = "pipe:1" // 向stdout写入输出
+)
+
+// inputURL 返回ffmpeg的输入地址，设置了input时从stdin读取
+// 管道不能定位，mp4/mov输入的moov必须位于文件开头（faststart或分片MP4），否则ffmpeg无法读取
+func inputURL(path string, input io.Reader) string {
+	if input != nil {
+		return pipeInput
+	}
+	return path
+}
+
+// outputURL 返回ffmpeg的输出地址，设置了output时写入stdout
+func outputURL(path string, output io.Writer) string {
+	if output != nil {
+		return pipeOutput
+	}
+	return path
+}
+
+// validateInput 校验输入路径与输入数据流必须且只能设置一个
+func validateInput(path string, input io.Reader) error {
+	if path == "" && input == nil {
+		return fmt.Errorf("%w: input path or reader is required", ErrInvalidParams)
+	}
+	if path != "" && input != nil {
+		return fmt.Errorf("%w: input path and reader are mutually exclusive", ErrInvalidParams)
+	}
+	return nil
+}
+
+// validateOutput 校验输出路径与输出数据流必须且只能设置一个
+// 写入数据流时必须显式指定容器，且输出不能需要定位；seekable为按当前参数输出时是否需要定位
+func validateOutput(path string, output io.Writer, explicit Container, seekable bool) error {
+	if path == "" && output == nil {
+		return fmt.Errorf("%w: output path or writer is required", ErrInvalidParams)
+	}
+	if output == nil {
+		return nil
+	}
+	if path != "" {
+		return fmt.Errorf("%w: output path and writer are mutually exclusive", ErrInvalidParams)
+	}
+	if explicit == "" {
+		return fmt.Errorf("%w: container is required when writing to a writer", ErrInvalidParams)
+	}
+	if seekable {
+		return fmt.Errorf("%w: %w: %s cannot be written to a writer", ErrInvalidParams, ErrSeekableOutput, explicit)
+	}
+	return nil
+}
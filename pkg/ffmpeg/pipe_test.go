@@ -0,0 +1,175 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestValidateOutput 测试写入数据流时的容器校验
+func TestValidateOutput(t *testing.T) {
+	var buf bytes.Buffer
+	tests := []struct {
+		name      string
+		path      string
+		container Container
+		seekable  bool
+		wantErr   bool
+	}{
+		{"streamable container", "", ContainerMKV, false, false},
+		{"mp4 needs seekable output", "", ContainerMP4, true, true},
+		{"m4a needs seekable output", "", ContainerM4A, true, true},
+		{"missing container", "", "", false, true},
+		{"path and writer", "out.mkv", ContainerMKV, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOutput(tt.path, &buf, tt.container, containerSpecs[tt.container].seekable)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("validateOutput() error = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidParams) {
+				t.Errorf("validateOutput() error = %v, want ErrInvalidParams", err)
+			}
+			if got := errors.Is(err, ErrSeekableOutput); got != tt.seekable {
+				t.Errorf("errors.Is(err, ErrSeekableOutput) = %v, want %v", got, tt.seekable)
+			}
+		})
+	}
+
+	if err := validateOutput("", nil, "", false); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("validateOutput() without output error = %v, want ErrInvalidParams", err)
+	}
+}
+
+// TestTranscodeStreams 测试通过stdin读取输入、通过stdout写入输出
+func TestTranscodeStreams(t *testing.T) {
+	argsFile := filepath.Join(t.TempDir(), "args")
+	// 把stdin原样写到stdout
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `echo "$@" > "`+argsFile+`"
+cat
+`)
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	var output bytes.Buffer
+	err = ffmpeg.Transcode(context.Background(), &TranscodeParams{
+		Input:     strings.NewReader("media data"),
+		Output:    &output,
+		Container: ContainerMKV,
+	})
+	if err != nil {
+		t.Fatalf("Transcode failed: %v", err)
+	}
+	if output.String() != "media data" {
+		t.Errorf("Expected output to be written to the writer, got %q", output.String())
+	}
+
+	data, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("Failed to read recorded arguments: %v", err)
+	}
+	args := strings.TrimSpace(string(data))
+	if !strings.Contains(args, "-i pipe:0") || !strings.HasSuffix(args, "-f matroska pipe:1") {
+		t.Errorf("Expected stdin input and stdout output, got args: %s", args)
+	}
+}
+
+// TestStreamInput 测试分段、关键帧、精灵图和预览动画通过stdin读取输入
+func TestStreamInput(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(f *FFmpeg, input io.Reader, dir string) error
+	}{
+		{"split video", func(f *FFmpeg, input io.Reader, dir string) error {
+			_, err := f.SplitVideo(&SplitVideoParams{Input: input, OutputDir: dir, SegmentTime: 10})
+			return err
+		}},
+		{"key frames", func(f *FFmpeg, input io.Reader, dir string) error {
+			_, err := f.ExtractKeyFrames(&ExtractKeyFramesParams{Input: input, OutputDir: dir, FrameInterval: 5})
+			return err
+		}},
+		{"sprite sheet", func(f *FFmpeg, input io.Reader, dir string) error {
+			_, err := f.GenerateSpriteSheet(context.Background(), &SpriteSheetParams{Input: input, OutputDir: dir, FrameInterval: 5})
+			return err
+		}},
+		{"preview animation", func(f *FFmpeg, input io.Reader, dir string) error {
+			return f.CreatePreviewAnimation(context.Background(), &PreviewAnimationParams{
+				Input:      input,
+				OutputPath: filepath.Join(dir, "preview.gif"),
+				TimeRange:  TimeRange{Duration: 3 * time.Second},
+			})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workDir := t.TempDir()
+			argsFile := filepath.Join(workDir, "args")
+			stdinFile := filepath.Join(workDir, "stdin")
+			// 记录参数和stdin，分段时写出空的分段列表
+			fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `echo "$@" > "`+argsFile+`"
+cat > "`+stdinFile+`"
+prev=""
+for arg; do
+	[ "$prev" = "-segment_list" ] && : > "$arg"
+	prev="$arg"
+done
+`)
+			ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, "", nil)
+			if err != nil {
+				t.Fatalf("Failed to create FFmpeg instance: %v", err)
+			}
+
+			if err := tt.run(ffmpeg, strings.NewReader("media data"), t.TempDir()); err != nil {
+				t.Fatalf("Failed to read from input: %v", err)
+			}
+			if data, _ := os.ReadFile(stdinFile); string(data) != "media data" {
+				t.Errorf("Expected the input on stdin, got %q", data)
+			}
+			if data, _ := os.ReadFile(argsFile); !strings.Contains(string(data), "-i pipe:0 ") {
+				t.Errorf("Expected stdin input, got args: %s", data)
+			}
+		})
+	}
+}
+
+// TestStreamInputRequiresPath 测试需要先获取输入信息或多次读取输入的操作拒绝数据流输入
+func TestStreamInputRequiresPath(t *testing.T) {
+	input := strings.NewReader("")
+	tests := []struct {
+		name   string
+		params interface{ Validate() error }
+	}{
+		{"audio language", &ExtractAudioParams{Input: input, OutputPath: "out.mp3", Language: "eng"}},
+		{"stream frames count", &StreamFramesParams{Input: input, Mode: KeyFrameModeCount, Count: 10}},
+		{"key frames count", &ExtractKeyFramesParams{Input: input, OutputDir: "out", Mode: KeyFrameModeCount, Count: 10}},
+		{"split by chapter", &SplitVideoParams{Input: input, OutputDir: "out", ByChapter: true}},
+		{"split by max size", &SplitVideoParams{Input: input, OutputDir: "out", MaxSize: 1 << 20}},
+		{"split by scene", &SplitVideoParams{Input: input, OutputDir: "out", SegmentTime: 10, Mode: SplitModeScene}},
+		{"split copy to audio", &SplitVideoParams{Input: input, OutputDir: "out", SegmentTime: 10, Format: ContainerM4A}},
+		{"preview highlights", &PreviewAnimationParams{Input: input, OutputPath: "out.gif"}},
+		{"transcode path and reader", &TranscodeParams{InputPath: "in.mp4", Input: input, OutputPath: "out.mkv"}},
+		{"sprite path and reader", &SpriteSheetParams{InputPath: "in.mp4", Input: input, OutputDir: "out", FrameInterval: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(); !errors.Is(err, ErrInvalidParams) {
+				t.Errorf("Validate() error = %v, want ErrInvalidParams", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPreviewWidth    = 320             // 预览动画默认宽度
+	defaultPreviewFPS      = 10              // 预览动画默认帧率
+	defaultHighlights      = 3               // 默认自动挑选的片段数
+	defaultHighlightLength = 2 * time.Second // 默认自动挑选的片段时长
+)
+
+// animationExtensions 文件扩展名对应的动画格式
+var animationExtensions = map[string]AnimationFormat{
+	".gif":  AnimationFormatGIF,
+	".webp": AnimationFormatWebP,
+}
+
+// format 返回实际使用的动画格式
+func (p *PreviewAnimationParams) format() AnimationFormat {
+	if p.Format != "" {
+		return p.Format
+	}
+	if format, ok := animationExtensions[strings.ToLower(filepath.Ext(p.OutputPath))]; ok {
+		return format
+	}
+	return AnimationFormatGIF
+}
+
+// width 返回实际使用的输出宽度
+func (p *PreviewAnimationParams) width() int {
+	if p.Width == 0 {
+		return defaultPreviewWidth
+	}
+	return p.Width
+}
+
+// fps 返回实际使用的输出帧率
+func (p *PreviewAnimationParams) fps() float64 {
+	if p.FPS == 0 {
+		return defaultPreviewFPS
+	}
+	return p.FPS
+}
+
+// highlights 返回实际使用的自动挑选片段数
+func (p *PreviewAnimationParams) highlights() int {
+	if p.Highlights == 0 {
+		return defaultHighlights
+	}
+	return p.Highlights
+}
+
+// highlightLength 返回实际使用的自动挑选片段时长
+func (p *PreviewAnimationParams) highlightLength() time.Duration {
+	if p.HighlightLength == 0 {
+		return defaultHighlightLength
+	}
+	return p.HighlightLength
+}
+
+// hasRange 返回是否指定了时间范围，未指定时自动挑选片段
+func (p *PreviewAnimationParams) hasRange() bool {
+	return p.Start > 0 || p.End > 0 || p.Duration > 0
+}
+
+// Validate 校验预览动画参数
+// 返回值:
+//
+//	error: 参数无效时返回包装了ErrInvalidParams的错误
+func (p *PreviewAnimationParams) Validate() error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidParams, fmt.Sprintf(format, args...))
+	}
+
+	if err := validateInput(p.InputPath, p.Input); err != nil {
+		return err
+	}
+	// 自动挑选片段需要先扫描整个文件，再从每个片段的位置重新读取
+	if p.Input != nil && !p.hasRange() {
+		return invalid("highlights require an input path, set a time range to read from input")
+	}
+	if p.OutputPath == "" {
+		return invalid("output path is required")
+	}
+	switch p.format() {
+	case AnimationFormatGIF, AnimationFormatWebP:
+	default:
+		return invalid("unsupported animation format %q", p.Format)
+	}
+	if p.Width < 0 || p.FPS < 0 || p.Loop < 0 {
+		return invalid("width, fps and loop must not be negative")
+	}
+	if p.Quality < 0 || p.Quality > maxImageQuality {
+		return invalid("quality %d is out of range 1-%d", p.Quality, maxImageQuality)
+	}
+	if p.Quality > 0 && p.format() == AnimationFormatGIF {
+		return invalid("gif does not support quality")
+	}
+	if p.Highlights < 0 || p.HighlightLength < 0 {
+		return invalid("highlights and highlight length must not be negative")
+	}
+	if p.hasRange() && (p.Highlights > 0 || p.HighlightLength > 0) {
+		return invalid("highlights cannot be combined with a time range")
+	}
+	// 每个片段都作为输入通过输入端定位截取，不支持输出端定位
+	if p.AccurateSeek {
+		return invalid("accurate seek is not supported for preview animations")
+	}
+	return p.timeRange().validate()
+}
+
+// previewClips 确定要截取的片段及其总时长
+// 指定了时间范围时只有该范围；否则挑选场景变化最明显的片段，输入文件太短时使用整个文件
+func (f *FFmpeg) previewClips(ctx context.Context, p *PreviewAnimationParams) ([]timeRange, time.Duration, error) {
+	if p.hasRange() {
+		trim := p.timeRange()
+		return []timeRange{trim}, f.rangeDuration(ctx, p.InputPath, trim), nil
+	}
+
+	info, err := f.Probe(ctx, p.InputPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	duration := info.Format.Duration
+	count, length := p.highlights(), p.highlightLength()
+	if duration <= time.Duration(count)*length {
+		return []timeRange{{}}, duration, nil
+	}
+
+	scenes, err := f.detectHighlightScenes(ctx, p.InputPath, duration)
+	if err != nil {
+		return nil, 0, err
+	}
+	var clips []timeRange
+	var total time.Duration
+	for _, start := range pickHighlights(scenes, duration, count, length) {
+		clip := timeRange{start: start, duration: length}
+		clips = append(clips, clip)
+		total += clip.within(duration)
+	}
+	return clips, total, nil
+}
+
+// detectHighlightScenes 扫描整个输入文件，返回场景切换处的帧及其场景分数
+// 扫描需要解码整个文件，按输入文件的时长duration报告进度；扫描只是生成预览的中间步骤，结束时不报告完成
+func (f *FFmpeg) detectHighlightScenes(ctx context.Context, inputPath string, duration time.Duration) ([]Frame, error) {
+	scan := &ExtractKeyFramesParams{InputPath: inputPath, Mode: KeyFrameModeScene}
+	parser := &frameInfoParser{}
+	err := f.run(ctx, &runOptions{
+		binary:   f.FFmpegPath,
+		args:     append(frameSelectArgs(scan, 0), "-an", "-sn", "-dn", "-f", "null", "-"),
+		stderr:   parser.parseLine,
+		progress: true,
+		partial:  true,
+		total:    duration,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parser.frames, nil
+}
+
+// pickHighlights 挑选count个互不重叠、时长为length的片段，返回按时间排序的开始时间
+// 优先选择场景分数最高的场景切换处，不足时在输入文件中均匀补充
+func pickHighlights(scenes []Frame, duration time.Duration, count int, length time.Duration) []time.Duration {
+	sorted := append([]Frame(nil), scenes...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].SceneScore > sorted[j].SceneScore })
+
+	var starts []time.Duration
+	add := func(start time.Duration) {
+		// 片段需要完整落在输入文件内
+		start = max(0, min(start, duration-length))
+		for _, other := range starts {
+			if start < other+length && other < start+length {
+				return
+			}
+		}
+		starts = append(starts, start)
+	}
+	for _, scene := range sorted {
+		if len(starts) == count {
+			break
+		}
+		add(scene.PTS)
+	}
+	for i := 0; i < count && len(starts) < count; i++ {
+		add(duration*time.Duration(i+1)/time.Duration(count+1) - length/2)
+	}
+
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+	return starts
+}
+
+// previewAnimationArgs 构建生成预览动画的完整参数
+// 每个片段作为一个独立的输入，通过输入端定位快速跳转，再由concat过滤器拼接
+func previewAnimationArgs(p *PreviewAnimationParams, clips []timeRange) []string {
+	args := []string{"-y"}
+	var labels strings.Builder
+	for i, clip := range clips {
+		// -t放在-i之前作为输入选项，只限制对应输入的时长
+		if clip.start > 0 {
+			args = append(args, "-ss", formatSeconds(clip.start))
+		}
+		if length := clip.length(); length > 0 {
+			args = append(args, "-t", formatSeconds(length))
+		}
+		args = append(args, "-i", inputURL(p.InputPath, p.Input))
+		fmt.Fprintf(&labels, "[%d:v]", i)
+	}
+
+	graph := labels.String()
+	if len(clips) > 1 {
+		graph += fmt.Sprintf("concat=n=%d:v=1:a=0,", len(clips))
+	}
+	graph += fmt.Sprintf("fps=%s,scale='min(iw,%d)':-1:flags=lanczos",
+		strconv.FormatFloat(p.fps(), 'f', -1, 64), p.width())
+
+	switch p.format() {
+	case AnimationFormatWebP:
+		args = append(args, "-filter_complex", graph, "-an", "-c:v", "libwebp")
+		if p.Quality > 0 {
+			args = append(args, "-quality", strconv.Itoa(p.Quality))
+		}
+		// webp muxer的-loop为播放次数，0表示无限循环
+		args = append(args, "-loop", strconv.Itoa(p.Loop), "-f", "webp")
+	default:
+		// 根据整个片段生成专用调色板，画质明显优于默认调色板
+		graph += ",split[frames][source];[source]palettegen[palette];[frames][palette]paletteuse"
+		// gif muxer的-loop为重复次数，-1表示只播放一次，0表示无限循环
+		loop := p.Loop - 1
+		switch p.Loop {
+		case 0:
+			loop = 0
+		case 1:
+			loop = -1
+		}
+		args = append(args, "-filter_complex", graph, "-an", "-loop", strconv.Itoa(loop), "-f", "gif")
+	}
+	return append(args, p.OutputPath)
+}
+
+// CreatePreviewAnimation 生成GIF或动画WebP预览
+// 指定了时间范围时截取该范围，否则自动挑选场景变化最明显的若干片段拼接在一起；
+// 处理进度通过实例的进度回调报告，自动挑选片段时先报告扫描整个文件的进度，再从0开始报告生成动画的进度，
+// 只在动画生成后报告一次completed；
+// ctx被取消或超时时会删除本次新建的不完整动画，返回的错误包装了context.Canceled或context.DeadlineExceeded
+// 参数:
+//
+//	ctx: 控制命令生命周期的上下文
+//	params: 预览动画参数配置
+//
+// 返回值:
+//
+//	error: 如果生成失败，返回错误信息
+//
+// 示例:
+//
+//	err := ffmpeg.CreatePreviewAnimation(ctx, &ffmpeg.PreviewAnimationParams{
+//	    InputPath:  "input.mp4",
+//	    OutputPath: "preview.gif",
+//	    TimeRange:  ffmpeg.TimeRange{Start: 30 * time.Second, Duration: 3 * time.Second},
+//	    Width:      480,
+//	    FPS:        12,
+//	})
+func (f *FFmpeg) CreatePreviewAnimation(ctx context.Context, params *PreviewAnimationParams) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	clips, total, err := f.previewClips(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	cleanup := removeNewOutputFile(params.OutputPath)
+	err = f.run(ctx, &runOptions{
+		binary:   f.FFmpegPath,
+		args:     previewAnimationArgs(params, clips),
+		stdin:    params.Input,
+		progress: true,
+		total:    total,
+	})
+	if err != nil {
+		// 取消或超时后删除本次新建的不完整动画
+		if ctx.Err() != nil {
+			cleanup()
+		}
+		return err
+	}
+
+	return nil
+}
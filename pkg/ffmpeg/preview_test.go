@@ -0,0 +1,235 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPreviewAnimationArgs 测试GIF和WebP的过滤器图及循环参数
+func TestPreviewAnimationArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		params PreviewAnimationParams
+		clips  []timeRange
+		want   []string
+	}{
+		{
+			name:   "gif from time range",
+			params: PreviewAnimationParams{InputPath: "in.mp4", OutputPath: "out.gif"},
+			clips:  []timeRange{{start: 30 * time.Second, duration: 3 * time.Second}},
+			want: []string{
+				"-y", "-ss", "30", "-t", "3", "-i", "in.mp4",
+				"-filter_complex", "[0:v]fps=10,scale='min(iw,320)':-1:flags=lanczos,split[frames][source];[source]palettegen[palette];[frames][palette]paletteuse",
+				"-an", "-loop", "0", "-f", "gif", "out.gif",
+			},
+		},
+		{
+			name:   "gif played once",
+			params: PreviewAnimationParams{InputPath: "in.mp4", OutputPath: "out.gif", Width: 480, FPS: 12.5, Loop: 1},
+			clips:  []timeRange{{}},
+			want: []string{
+				"-y", "-i", "in.mp4",
+				"-filter_complex", "[0:v]fps=12.5,scale='min(iw,480)':-1:flags=lanczos,split[frames][source];[source]palettegen[palette];[frames][palette]paletteuse",
+				"-an", "-loop", "-1", "-f", "gif", "out.gif",
+			},
+		},
+		{
+			name:   "webp from highlights",
+			params: PreviewAnimationParams{InputPath: "in.mp4", OutputPath: "out.webp", Quality: 70, Loop: 2},
+			clips:  []timeRange{{start: 10 * time.Second, duration: 2 * time.Second}, {start: 50 * time.Second, duration: 2 * time.Second}},
+			want: []string{
+				"-y", "-ss", "10", "-t", "2", "-i", "in.mp4", "-ss", "50", "-t", "2", "-i", "in.mp4",
+				"-filter_complex", "[0:v][1:v]concat=n=2:v=1:a=0,fps=10,scale='min(iw,320)':-1:flags=lanczos",
+				"-an", "-c:v", "libwebp", "-quality", "70", "-loop", "2", "-f", "webp", "out.webp",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := previewAnimationArgs(&tt.params, tt.clips); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("previewAnimationArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPreviewAnimationParamsValidate 测试无效的预览动画参数被拒绝
+func TestPreviewAnimationParamsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		params PreviewAnimationParams
+	}{
+		{"missing output", PreviewAnimationParams{InputPath: "in.mp4"}},
+		{"unknown format", PreviewAnimationParams{InputPath: "in.mp4", OutputPath: "out.apng", Format: "apng"}},
+		{"gif quality", PreviewAnimationParams{InputPath: "in.mp4", OutputPath: "out.gif", Quality: 80}},
+		{"negative loop", PreviewAnimationParams{InputPath: "in.mp4", OutputPath: "out.gif", Loop: -1}},
+		{"highlights with range", PreviewAnimationParams{InputPath: "in.mp4", OutputPath: "out.gif", TimeRange: TimeRange{Start: time.Second}, Highlights: 2}},
+		{"accurate seek", PreviewAnimationParams{InputPath: "in.mp4", OutputPath: "out.gif", TimeRange: TimeRange{Start: time.Second, AccurateSeek: true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(); !errors.Is(err, ErrInvalidParams) {
+				t.Errorf("Validate() error = %v, want ErrInvalidParams", err)
+			}
+		})
+	}
+}
+
+// TestPickHighlights 测试优先选择场景分数最高且互不重叠的片段
+func TestPickHighlights(t *testing.T) {
+	scenes := []Frame{
+		{PTS: 10 * time.Second, SceneScore: 0.5},
+		{PTS: 11 * time.Second, SceneScore: 0.9}, // 分数最高
+		{PTS: 40 * time.Second, SceneScore: 0.7},
+		{PTS: 99 * time.Second, SceneScore: 0.6}, // 片段超出结尾，向前移动
+	}
+
+	tests := []struct {
+		name   string
+		scenes []Frame
+		count  int
+		want   []time.Duration
+	}{
+		{"top scenes", scenes, 3, []time.Duration{11 * time.Second, 40 * time.Second, 98 * time.Second}},
+		{"overlapping scene skipped", scenes, 4, []time.Duration{11 * time.Second, 19 * time.Second, 40 * time.Second, 98 * time.Second}},
+		{"no scenes", nil, 3, []time.Duration{24 * time.Second, 49 * time.Second, 74 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pickHighlights(tt.scenes, 100*time.Second, tt.count, 2*time.Second); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pickHighlights() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCreatePreviewAnimationHighlights 测试未指定时间范围时先扫描场景再截取片段，两次运行都报告进度，只在最后报告一次完成
+func TestCreatePreviewAnimationHighlights(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping - progress is read from an extra file descriptor")
+	}
+
+	// 扫描时处理到60秒，生成动画时处理到1秒
+	argsFile := filepath.Join(t.TempDir(), "args")
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `case "$*" in
+*showinfo*)
+	printf 'out_time_us=60000000\nprogress=continue\n' >&3
+	echo "[Parsed_metadata_1 @ 0x1] lavfi.scene_score=0.800000" >&2
+	echo "[Parsed_showinfo_2 @ 0x2] n:   0 pts:  30000 pts_time:30  s:1920x1080 type:P" >&2
+	;;
+*)
+	printf 'out_time_us=1000000\nprogress=continue\n' >&3
+	echo "$@" > "`+argsFile+`"
+	;;
+esac
+`)
+
+	var progresses []Progress
+	ffmpeg := &FFmpeg{FFmpegPath: fakeFFmpeg, FFprobePath: writeFakeFFprobe(t), Callback: func(progress *Progress) {
+		progresses = append(progresses, *progress)
+	}}
+	err := ffmpeg.CreatePreviewAnimation(context.Background(), &PreviewAnimationParams{
+		InputPath:  "input.mp4",
+		OutputPath: "preview.gif",
+		Highlights: 2,
+	})
+	if err != nil {
+		t.Fatalf("CreatePreviewAnimation failed: %v", err)
+	}
+
+	data, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("Failed to read recorded arguments: %v", err)
+	}
+	// 输入文件时长120.12秒：场景切换处30秒，以及均匀补充的位置
+	if !strings.Contains(string(data), "-ss 30 -t 2 -i input.mp4 -ss 39.04 -t 2 -i input.mp4") {
+		t.Errorf("Expected clips at the scene change and an evenly spaced position, got args: %s", data)
+	}
+
+	// 扫描按输入文件的120.12秒计算进度，生成动画按两个2秒片段的总时长计算
+	want := []struct {
+		total      int64
+		percentage float64
+	}{{120120, 60 / 120.12 * 100}, {4000, 25}, {0, 100}}
+	if len(progresses) != len(want) {
+		t.Fatalf("Expected %d progress callbacks, got %d: %+v", len(want), len(progresses), progresses)
+	}
+	for i, w := range want {
+		if progresses[i].Total != w.total || math.Abs(progresses[i].Percentage-w.percentage) > 0.01 {
+			t.Errorf("Progress %d = %+v, want total %d and percentage %.2f", i, progresses[i], w.total, w.percentage)
+		}
+	}
+	for i, progress := range progresses[:len(progresses)-1] {
+		if progress.Status == "completed" {
+			t.Errorf("Progress %d reported completed before the animation was encoded", i)
+		}
+	}
+}
+
+// TestCreatePreviewAnimationTimeout 测试超时后删除本次生成的不完整动画
+func TestCreatePreviewAnimationTimeout(t *testing.T) {
+	// 模拟写出部分输出后长时间运行的ffmpeg
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `for last; do :; done
+echo partial > "$last"
+sleep 30
+`)
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "preview.gif")
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	err = ffmpeg.CreatePreviewAnimation(ctx, &PreviewAnimationParams{
+		InputPath:  "input.mp4",
+		OutputPath: outputPath,
+		TimeRange:  TimeRange{Duration: 3 * time.Second},
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected error wrapping context.DeadlineExceeded, got %v", err)
+	}
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Fatalf("Expected partial output %s to be removed", outputPath)
+	}
+}
+
+// TestCreatePreviewAnimationCancelKeepsExistingOutput 测试在ffmpeg打开输出之前取消时不删除已存在的动画
+func TestCreatePreviewAnimationCancelKeepsExistingOutput(t *testing.T) {
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `sleep 30
+`)
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "preview.gif")
+	if err := os.WriteFile(outputPath, []byte("keep"), 0644); err != nil {
+		t.Fatalf("Failed to write existing output: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	err = ffmpeg.CreatePreviewAnimation(ctx, &PreviewAnimationParams{
+		InputPath:  "input.mp4",
+		OutputPath: outputPath,
+		TimeRange:  TimeRange{Duration: 3 * time.Second},
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected error wrapping context.DeadlineExceeded, got %v", err)
+	}
+	if data, err := os.ReadFile(outputPath); err != nil || string(data) != "keep" {
+		t.Fatalf("Expected pre-existing output to be kept, got %q, %v", data, err)
+	}
+}
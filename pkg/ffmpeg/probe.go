@@ -0,0 +1,238 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// probeOutput ffprobe JSON输出的原始结构
+// ffprobe将大部分数值以字符串形式输出，解析后再转换为MediaInfo
+type probeOutput struct {
+	Format   probeFormat    `json:"format"`
+	Streams  []probeStream  `json:"streams"`
+	Chapters []probeChapter `json:"chapters"`
+}
+
+type probeFormat struct {
+	Filename       string            `json:"filename"`
+	NbStreams      int               `json:"nb_streams"`
+	FormatName     string            `json:"format_name"`
+	FormatLongName string            `json:"format_long_name"`
+	StartTime      string            `json:"start_time"`
+	Duration       string            `json:"duration"`
+	Size           string            `json:"size"`
+	BitRate        string            `json:"bit_rate"`
+	Tags           map[string]string `json:"tags"`
+}
+
+type probeStream struct {
+	Index         int               `json:"index"`
+	CodecName     string            `json:"codec_name"`
+	CodecLongName string            `json:"codec_long_name"`
+	Profile       string            `json:"profile"`
+	CodecType     string            `json:"codec_type"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	PixFmt        string            `json:"pix_fmt"`
+	AvgFrameRate  string            `json:"avg_frame_rate"`
+	RFrameRate    string            `json:"r_frame_rate"`
+	SampleRate    string            `json:"sample_rate"`
+	Channels      int               `json:"channels"`
+	ChannelLayout string            `json:"channel_layout"`
+	StartTime     string            `json:"start_time"`
+	Duration      string            `json:"duration"`
+	BitRate       string            `json:"bit_rate"`
+	Disposition   map[string]int    `json:"disposition"`
+	Tags          map[string]string `json:"tags"`
+}
+
+type probeChapter struct {
+	ID        int64             `json:"id"`
+	StartTime string            `json:"start_time"`
+	EndTime   string            `json:"end_time"`
+	Tags      map[string]string `json:"tags"`
+}
+
+// Probe 使用ffprobe获取媒体文件的完整信息
+// 参数:
+//
+//	ctx: 控制命令生命周期的上下文
+//	inputPath: 输入媒体文件路径
+//
+// 返回值:
+//
+//	*MediaInfo: 容器、媒体流及章节信息
+//	error: 如果获取失败，返回错误信息
+//
+// 示例:
+//
+//	info, err := ffmpeg.Probe(ctx, "input.mp4")
+//	for _, stream := range info.VideoStreams() {
+//	    fmt.Printf("%s %dx%d\n", stream.CodecName, stream.Width, stream.Height)
+//	}
+func (f *FFmpeg) Probe(ctx context.Context, inputPath string) (*MediaInfo, error) {
+	var stdout bytes.Buffer
+	err := f.run(ctx, &runOptions{
+		binary: f.ffprobeBinary(),
+		args: []string{"-v", "error", "-print_format", "json",
+			"-show_format", "-show_streams", "-show_chapters", inputPath},
+		stdout: &stdout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseProbeOutput(stdout.Bytes())
+}
+
+// VideoStreams 返回所有视频流（不包括封面图片）
+func (m *MediaInfo) VideoStreams() []StreamInfo {
+	var streams []StreamInfo
+	for _, stream := range m.Streams {
+		if stream.Type == StreamTypeVideo && !stream.Disposition.AttachedPic {
+			streams = append(streams, stream)
+		}
+	}
+	return streams
+}
+
+// AudioStreams 返回所有音频流
+func (m *MediaInfo) AudioStreams() []StreamInfo {
+	return m.streamsOfType(StreamTypeAudio)
+}
+
+// SubtitleStreams 返回所有字幕流
+func (m *MediaInfo) SubtitleStreams() []StreamInfo {
+	return m.streamsOfType(StreamTypeSubtitle)
+}
+
+func (m *MediaInfo) streamsOfType(streamType StreamType) []StreamInfo {
+	var streams []StreamInfo
+	for _, stream := range m.Streams {
+		if stream.Type == streamType {
+			streams = append(streams, stream)
+		}
+	}
+	return streams
+}
+
+// parseProbeOutput 将ffprobe的JSON输出转换为MediaInfo
+func parseProbeOutput(data []byte) (*MediaInfo, error) {
+	var raw probeOutput
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse probe output: %w", err)
+	}
+
+	info := &MediaInfo{
+		Format: FormatInfo{
+			Filename:       raw.Format.Filename,
+			FormatName:     raw.Format.FormatName,
+			FormatLongName: raw.Format.FormatLongName,
+			StartTime:      parseSeconds(raw.Format.StartTime),
+			Duration:       parseSeconds(raw.Format.Duration),
+			Size:           parseInt(raw.Format.Size),
+			BitRate:        parseInt(raw.Format.BitRate),
+			StreamCount:    raw.Format.NbStreams,
+			Tags:           raw.Format.Tags,
+		},
+	}
+
+	for _, s := range raw.Streams {
+		frameRate := parseRational(s.AvgFrameRate)
+		if frameRate == 0 {
+			frameRate = parseRational(s.RFrameRate)
+		}
+
+		info.Streams = append(info.Streams, StreamInfo{
+			Index:         s.Index,
+			Type:          StreamType(s.CodecType),
+			CodecName:     s.CodecName,
+			CodecLongName: s.CodecLongName,
+			Profile:       s.Profile,
+			BitRate:       parseInt(s.BitRate),
+			StartTime:     parseSeconds(s.StartTime),
+			Duration:      parseSeconds(s.Duration),
+			Width:         s.Width,
+			Height:        s.Height,
+			PixelFormat:   s.PixFmt,
+			FrameRate:     frameRate,
+			SampleRate:    int(parseInt(s.SampleRate)),
+			Channels:      s.Channels,
+			ChannelLayout: s.ChannelLayout,
+			Language:      s.Tags["language"],
+			Title:         s.Tags["title"],
+			Disposition: Disposition{
+				Default:         s.Disposition["default"] == 1,
+				Dub:             s.Disposition["dub"] == 1,
+				Original:        s.Disposition["original"] == 1,
+				Comment:         s.Disposition["comment"] == 1,
+				Lyrics:          s.Disposition["lyrics"] == 1,
+				Karaoke:         s.Disposition["karaoke"] == 1,
+				Forced:          s.Disposition["forced"] == 1,
+				HearingImpaired: s.Disposition["hearing_impaired"] == 1,
+				VisualImpaired:  s.Disposition["visual_impaired"] == 1,
+				AttachedPic:     s.Disposition["attached_pic"] == 1,
+			},
+			Tags: s.Tags,
+		})
+	}
+
+	for _, c := range raw.Chapters {
+		info.Chapters = append(info.Chapters, Chapter{
+			ID:    c.ID,
+			Start: parseSeconds(c.StartTime),
+			End:   parseSeconds(c.EndTime),
+			Title: c.Tags["title"],
+			Tags:  c.Tags,
+		})
+	}
+
+	return info, nil
+}
+
+// parseSeconds 将以秒为单位的字符串（如"12.345"）转换为time.Duration
+// 无法解析（如"N/A"）时返回0
+func parseSeconds(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// parseInt 将整数字符串转换为int64，无法解析时返回0
+func parseInt(value string) int64 {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// parseRational 将分数形式的字符串（如"30000/1001"）转换为浮点数
+// 无法解析或分母为0时返回0
+func parseRational(value string) float64 {
+	num, den, found := strings.Cut(value, "/")
+	if !found {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0
+		}
+		return f
+	}
+
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return n / d
+}
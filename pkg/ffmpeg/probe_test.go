@@ -0,0 +1,95 @@
+package ffmpeg
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeFakeFFprobe 生成一个输出testdata/probe.json的模拟ffprobe
+func writeFakeFFprobe(t *testing.T) string {
+	t.Helper()
+	fixture, err := filepath.Abs(filepath.Join("testdata", "probe.json"))
+	if err != nil {
+		t.Fatalf("Failed to resolve fixture path: %v", err)
+	}
+	return writeFakeBinary(t, "ffprobe", `cat "`+fixture+`"`)
+}
+
+// TestProbe 测试通过ffprobe获取媒体信息并解析为类型化结构
+func TestProbe(t *testing.T) {
+	ffmpeg := &FFmpeg{FFprobePath: writeFakeFFprobe(t)}
+
+	info, err := ffmpeg.Probe(context.Background(), "input.mp4")
+	if err != nil {
+		t.Fatalf("Failed to probe: %v", err)
+	}
+
+	// 检查容器信息
+	if info.Format.FormatName != "mov,mp4,m4a,3gp,3g2,mj2" {
+		t.Fatalf("Unexpected format name: %s", info.Format.FormatName)
+	}
+	if info.Format.Duration != 120120*time.Millisecond {
+		t.Fatalf("Unexpected duration: %v", info.Format.Duration)
+	}
+	if info.Format.Size != 69800000 || info.Format.BitRate != 4648684 {
+		t.Fatalf("Unexpected size/bitrate: %d/%d", info.Format.Size, info.Format.BitRate)
+	}
+	if info.Format.Tags["title"] != "Sample" {
+		t.Fatalf("Unexpected format tags: %v", info.Format.Tags)
+	}
+
+	// 检查视频流
+	video := info.VideoStreams()
+	if len(video) != 1 {
+		t.Fatalf("Expected 1 video stream, got %d", len(video))
+	}
+	if video[0].CodecName != "h264" || video[0].Profile != "High" || video[0].Width != 1920 || video[0].Height != 1080 {
+		t.Fatalf("Unexpected video stream: %+v", video[0])
+	}
+	if video[0].PixelFormat != "yuv420p" || video[0].FrameRate < 29.96 || video[0].FrameRate > 29.98 {
+		t.Fatalf("Unexpected pixel format/frame rate: %s/%f", video[0].PixelFormat, video[0].FrameRate)
+	}
+
+	// 检查音频流
+	audio := info.AudioStreams()
+	if len(audio) != 2 {
+		t.Fatalf("Expected 2 audio streams, got %d", len(audio))
+	}
+	if audio[0].SampleRate != 48000 || audio[0].Channels != 2 || audio[0].Language != "eng" || !audio[0].Disposition.Default {
+		t.Fatalf("Unexpected audio stream: %+v", audio[0])
+	}
+	if audio[1].Language != "chi" || !audio[1].Disposition.Comment {
+		t.Fatalf("Unexpected second audio stream: %+v", audio[1])
+	}
+
+	// 检查字幕流
+	subtitles := info.SubtitleStreams()
+	if len(subtitles) != 1 || !subtitles[0].Disposition.Forced {
+		t.Fatalf("Unexpected subtitle streams: %+v", subtitles)
+	}
+
+	// 检查章节
+	if len(info.Chapters) != 2 {
+		t.Fatalf("Expected 2 chapters, got %d", len(info.Chapters))
+	}
+	if info.Chapters[1].Title != "Ending" || info.Chapters[1].Start != time.Minute || info.Chapters[1].End != 120120*time.Millisecond {
+		t.Fatalf("Unexpected chapter: %+v", info.Chapters[1])
+	}
+}
+
+// TestParseRational 测试解析分数形式的帧率
+func TestParseRational(t *testing.T) {
+	tests := map[string]float64{
+		"25/1": 25,
+		"0/0":  0,
+		"24":   24,
+		"N/A":  0,
+	}
+	for input, want := range tests {
+		if got := parseRational(input); got != want {
+			t.Errorf("parseRational(%q) = %f, want %f", input, got, want)
+		}
+	}
+}
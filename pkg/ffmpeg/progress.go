@@ -0,0 +1,118 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// progressParser 解析ffmpeg -progress输出的key=value块
+// 每个块以progress=continue或progress=end结尾
+type progressParser struct {
+	total  time.Duration     // 处理范围的总时长，为0时无法计算百分比和ETA
+	values map[string]string // 当前块已读取的键值
+}
+
+// newProgressParser 创建进度解析器
+func newProgressParser(total time.Duration) *progressParser {
+	return &progressParser{total: total, values: make(map[string]string)}
+}
+
+// parseLine 解析一行进度输出
+// 读取到块结束标记时返回该块对应的Progress，否则返回nil
+func (p *progressParser) parseLine(line string) *Progress {
+	key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+	if !found {
+		return nil
+	}
+	if key != "progress" {
+		p.values[key] = strings.TrimSpace(value)
+		return nil
+	}
+
+	progress := p.build()
+	p.values = make(map[string]string)
+	return progress
+}
+
+// build 根据当前块的键值构造Progress
+func (p *progressParser) build() *Progress {
+	current := p.outTime()
+	progress := &Progress{
+		Current: current.Milliseconds(),
+		Total:   p.total.Milliseconds(),
+		Status:  "processing",
+		Frame:   parseInt(p.values["frame"]),
+		OutSize: parseInt(p.values["total_size"]),
+	}
+
+	progress.FPS, _ = strconv.ParseFloat(p.values["fps"], 64)
+	progress.Bitrate, _ = strconv.ParseFloat(strings.TrimSuffix(p.values["bitrate"], "kbits/s"), 64)
+	progress.Speed, _ = strconv.ParseFloat(strings.TrimSuffix(p.values["speed"], "x"), 64)
+
+	// 计算百分比
+	if p.total > 0 {
+		progress.Percentage = float64(current) / float64(p.total) * 100
+		if progress.Percentage > 100 {
+			progress.Percentage = 100
+		}
+	}
+
+	// 根据处理速度估算剩余时间
+	if p.total > current && progress.Speed > 0 {
+		progress.ETA = time.Duration(float64(p.total-current) / progress.Speed)
+	}
+
+	return progress
+}
+
+// outTime 返回当前块的输出时间位置
+// 优先使用out_time_us，不可用时解析HH:MM:SS.micro格式的out_time
+func (p *progressParser) outTime() time.Duration {
+	if us, err := strconv.ParseInt(p.values["out_time_us"], 10, 64); err == nil && us > 0 {
+		return time.Duration(us) * time.Microsecond
+	}
+
+	parts := strings.Split(p.values["out_time"], ":")
+	if len(parts) != 3 {
+		return 0
+	}
+	hours, err1 := strconv.Atoi(parts[0])
+	minutes, err2 := strconv.Atoi(parts[1])
+	seconds, err3 := strconv.ParseFloat(parts[2], 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds*float64(time.Second))
+}
+
+// readProgress 从进度管道逐行读取并回调，直到管道关闭
+func (f *FFmpeg) readProgress(r io.Reader, total time.Duration) {
+	parser := newProgressParser(total)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if progress := parser.parseLine(scanner.Text()); progress != nil && f.Callback != nil {
+			f.Callback(progress)
+		}
+	}
+	// 确保ffmpeg不会因管道写满而阻塞
+	io.Copy(io.Discard, r)
+}
+
+// inputDuration 通过ffprobe获取输入文件的时长，用于计算进度百分比
+// 未设置进度回调、输入来自io.Reader（inputPath为空）或获取失败时返回0
+func (f *FFmpeg) inputDuration(ctx context.Context, inputPath string) time.Duration {
+	if f.Callback == nil || inputPath == "" {
+		return 0
+	}
+
+	info, err := f.Probe(ctx, inputPath)
+	if err != nil {
+		f.logger().DebugContext(ctx, "failed to probe input duration", "input", inputPath, "error", err)
+		return 0
+	}
+	return info.Format.Duration
+}
@@ -0,0 +1,82 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// validateTimeout 校验候选二进制文件时执行-version的超时时间
+const validateTimeout = 10 * time.Second
+
+// ErrBinaryNotFound 按查找顺序尝试所有来源后仍未找到可用的二进制文件
+var ErrBinaryNotFound = errors.New("ffmpeg binary not found")
+
+// binaryLookup 描述一个二进制文件（ffmpeg或ffprobe）的查找方式
+type binaryLookup struct {
+	name     string                                   // 可执行文件名，用于$PATH查找和-version输出校验
+	explicit string                                   // 调用方显式指定的路径
+	envVar   string                                   // 环境变量名
+	extract  func(extractPath string) (string, error) // 内置二进制释放函数
+}
+
+// resolveBinary 按显式指定、环境变量、$PATH、内置二进制的顺序查找可用的二进制文件
+// 每个候选都会通过执行-version进行校验；显式指定的路径校验失败时直接返回错误，
+// 其余来源校验失败时记录日志并尝试下一个来源
+func resolveBinary(lookup binaryLookup, extractPath string, logger *slog.Logger) (string, BinarySource, error) {
+	// 1. 显式指定
+	if lookup.explicit != "" {
+		if err := validateBinary(lookup.explicit, lookup.name); err != nil {
+			return "", "", err
+		}
+		return lookup.explicit, BinarySourceExplicit, nil
+	}
+
+	// 2. 环境变量
+	if envPath := os.Getenv(lookup.envVar); envPath != "" {
+		err := validateBinary(envPath, lookup.name)
+		if err == nil {
+			return envPath, BinarySourceEnv, nil
+		}
+		logger.Warn("ignoring invalid binary from environment", "env", lookup.envVar, "path", envPath, "error", err)
+	}
+
+	// 3. $PATH
+	if pathBinary, err := exec.LookPath(lookup.name); err == nil {
+		err := validateBinary(pathBinary, lookup.name)
+		if err == nil {
+			return pathBinary, BinarySourcePath, nil
+		}
+		logger.Warn("ignoring invalid binary from PATH", "path", pathBinary, "error", err)
+	}
+
+	// 4. 内置二进制
+	embeddedPath, err := lookup.extract(extractPath)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: checked %s, $PATH and embedded binaries: %w", ErrBinaryNotFound, lookup.envVar, err)
+	}
+	if err := validateBinary(embeddedPath, lookup.name); err != nil {
+		return "", "", err
+	}
+	return embeddedPath, BinarySourceEmbedded, nil
+}
+
+// validateBinary 执行"<path> -version"校验二进制文件是否可用
+func validateBinary(path, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), validateTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, "-version").Output()
+	if err != nil {
+		return fmt.Errorf("invalid %s binary %s: %w", name, path, err)
+	}
+	if !strings.Contains(string(output), name+" version") {
+		return fmt.Errorf("invalid %s binary %s: unexpected -version output", name, path)
+	}
+	return nil
+}
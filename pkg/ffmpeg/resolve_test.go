@@ -0,0 +1,79 @@
+package ffmpeg
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/yxx1912008/linker-ffmpeg-go/internal/ffmpeg"
+)
+
+// TestResolveOrder 测试二进制文件按显式指定、环境变量、$PATH的顺序查找
+func TestResolveOrder(t *testing.T) {
+	explicitFFmpeg := writeFakeBinary(t, "ffmpeg", `echo "ffmpeg version explicit"`)
+	envFFmpeg := writeFakeBinary(t, "ffmpeg", `echo "ffmpeg version env"`)
+	envFFprobe := writeFakeBinary(t, "ffprobe", `echo "ffprobe version env"`)
+	pathFFmpeg := writeFakeBinary(t, "ffmpeg", `echo "ffmpeg version path"`)
+
+	t.Setenv("PATH", filepath.Dir(pathFFmpeg))
+	t.Setenv("FFMPEG_PATH", envFFmpeg)
+	t.Setenv("FFPROBE_PATH", envFFprobe)
+
+	// 显式指定优先于环境变量
+	f, err := NewFFmpegWithOptions(&Options{FFmpegPath: explicitFFmpeg})
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+	if f.FFmpegPath != explicitFFmpeg || f.FFmpegSource != BinarySourceExplicit {
+		t.Fatalf("Expected explicit ffmpeg, got %s (%s)", f.FFmpegPath, f.FFmpegSource)
+	}
+	if f.FFprobePath != envFFprobe || f.FFprobeSource != BinarySourceEnv {
+		t.Fatalf("Expected ffprobe from environment, got %s (%s)", f.FFprobePath, f.FFprobeSource)
+	}
+
+	// 环境变量优先于$PATH
+	f, err = NewFFmpegWithOptions(nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+	if f.FFmpegPath != envFFmpeg || f.FFmpegSource != BinarySourceEnv {
+		t.Fatalf("Expected ffmpeg from environment, got %s (%s)", f.FFmpegPath, f.FFmpegSource)
+	}
+
+	// 环境变量指向的文件无效时回退到$PATH
+	t.Setenv("FFMPEG_PATH", writeFakeScript(t, "ffmpeg", `exit 1`))
+	f, err = NewFFmpeg(nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+	if f.FFmpegPath != pathFFmpeg || f.FFmpegSource != BinarySourcePath {
+		t.Fatalf("Expected ffmpeg from PATH, got %s (%s)", f.FFmpegPath, f.FFmpegSource)
+	}
+}
+
+// TestResolveInvalidExplicit 测试显式指定的二进制文件无效时直接返回错误
+func TestResolveInvalidExplicit(t *testing.T) {
+	notFFmpeg := writeFakeScript(t, "ffmpeg", `echo "something else"`)
+
+	if _, err := NewFFmpegWithOptions(&Options{FFmpegPath: notFFmpeg}); err == nil {
+		t.Fatal("Expected error for binary that is not ffmpeg")
+	}
+}
+
+// TestResolveNotFound 测试所有来源都不可用时返回ErrBinaryNotFound
+func TestResolveNotFound(t *testing.T) {
+	if ffmpeg.HasFFmpegBinary() {
+		t.Skip("Skipping - this platform has an embedded binary")
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("FFMPEG_PATH", "")
+
+	f, err := NewFFmpeg(nil)
+	if f != nil {
+		t.Fatal("Expected nil instance when no binary is found")
+	}
+	if !errors.Is(err, ErrBinaryNotFound) || !errors.Is(err, ErrNoEmbeddedBinary) {
+		t.Fatalf("Expected ErrBinaryNotFound wrapping ErrNoEmbeddedBinary, got %v", err)
+	}
+}
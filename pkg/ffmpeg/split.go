@@ -0,0 +1,550 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultSceneThreshold SplitModeScene默认的场景切换阈值
+const defaultSceneThreshold = 0.4
+
+// splitWorkDirPattern 每次切分使用的临时目录名，位于输出目录下以便直接重命名到输出目录
+const splitWorkDirPattern = ".linker-ffmpeg-split-*"
+
+// showinfoTimePattern 匹配showinfo过滤器输出中的pts_time字段
+var showinfoTimePattern = regexp.MustCompile(`pts_time:\s*(-?[0-9.]+)`)
+
+// mode 返回实际使用的分段方式
+func (p *SplitVideoParams) mode() SplitMode {
+	if p.Mode == "" {
+		return SplitModeCopy
+	}
+	return p.Mode
+}
+
+// format 返回实际使用的分段容器格式
+// 未指定时沿用输入文件的容器格式，无法识别时使用mp4
+func (p *SplitVideoParams) format() Container {
+	if p.Format != "" {
+		return p.Format
+	}
+	if format := containerFromPath(p.InputPath); containerSpecs[format].muxer != "" {
+		return format
+	}
+	return ContainerMP4
+}
+
+// sceneThreshold 返回实际使用的场景切换阈值
+func (p *SplitVideoParams) sceneThreshold() float64 {
+	if p.SceneThreshold == 0 {
+		return defaultSceneThreshold
+	}
+	return p.SceneThreshold
+}
+
+// Validate 校验视频分段参数
+// 返回值:
+//
+//	error: 参数无效时返回包装了ErrInvalidParams的错误
+func (p *SplitVideoParams) Validate() error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidParams, fmt.Sprintf(format, args...))
+	}
+
+	if err := validateInput(p.InputPath, p.Input); err != nil {
+		return err
+	}
+	if p.OutputDir == "" {
+		return invalid("output directory is required")
+	}
+	if p.SegmentTime < 0 || p.MaxSize < 0 {
+		return invalid("segment time and max size must not be negative")
+	}
+	strategies := 0
+	for _, set := range []bool{p.SegmentTime > 0, len(p.CutPoints) > 0, p.ByChapter, p.MaxSize > 0} {
+		if set {
+			strategies++
+		}
+	}
+	if strategies == 0 {
+		return invalid("one of segment time, cut points, chapters or max size is required")
+	}
+	if strategies > 1 {
+		return invalid("segment time, cut points, chapters and max size are mutually exclusive")
+	}
+	for _, cut := range p.CutPoints {
+		if cut <= 0 {
+			return invalid("cut points must be positive")
+		}
+	}
+	format := p.format()
+	spec, ok := containerSpecs[format]
+	if !ok {
+		return invalid("unsupported format %q", format)
+	}
+	switch p.mode() {
+	case SplitModeCopy, SplitModeReencode:
+	case SplitModeScene:
+		if p.SegmentTime == 0 {
+			return invalid("scene split mode requires segment time")
+		}
+		if len(spec.videoCodecs) == 0 {
+			return invalid("scene split mode requires a video format, got %q", format)
+		}
+	default:
+		return invalid("unsupported split mode %q", p.Mode)
+	}
+	// 数据流只能读取一次，无法先获取输入文件信息或检测场景
+	if p.Input != nil {
+		switch {
+		case p.ByChapter || p.MaxSize > 0:
+			return invalid("splitting by chapters or max size requires an input path")
+		case p.mode() == SplitModeScene:
+			return invalid("scene split mode requires an input path")
+		case p.mode() == SplitModeCopy && len(spec.videoCodecs) == 0:
+			return invalid("copying into audio format %q requires an input path", format)
+		}
+	}
+	if p.SceneThreshold < 0 || p.SceneThreshold > 1 {
+		return invalid("scene threshold %g is out of range 0-1", p.SceneThreshold)
+	}
+	return p.timeRange().validate()
+}
+
+// splitPlan 描述segment muxer的切分方式
+// interval大于0时按固定时长切分，否则在cuts中的每个时间点切分，cuts为空时整个范围输出为一个分段
+type splitPlan struct {
+	interval time.Duration   // 固定分段时长
+	cuts     []time.Duration // 切分时间点，相对于裁剪范围的开始时间
+	audio    *StreamInfo     // 直接复制到音频格式时选中的源音频流，为nil时由ffmpeg自动选择
+	start    time.Duration   // 第一个分段在输入文件中的实际开始时间
+}
+
+// maxSizeHeadroom 按码率估算分段时长时预留的余量，抵消码率波动和容器开销
+const maxSizeHeadroom = 0.9
+
+// maxSizeAttempts 按大小分段时最多切分的次数，包括第一次按平均码率估算的切分
+const maxSizeAttempts = 3
+
+// planSplit 根据切分依据计算切分方式
+// 直接复制到音频格式时还需要确认源音频编码可以放入该格式，否则ffmpeg会在写入分段时失败
+func (f *FFmpeg) planSplit(ctx context.Context, p *SplitVideoParams) (splitPlan, error) {
+	plan, err := f.planCuts(ctx, p)
+	if err != nil {
+		return splitPlan{}, err
+	}
+	plan.start = p.timeRange().start
+	format := p.format()
+	spec := containerSpecs[format]
+	if p.mode() != SplitModeCopy {
+		return plan, nil
+	}
+	if len(spec.videoCodecs) > 0 {
+		plan.start = f.keyFrameStart(ctx, p)
+		return plan, nil
+	}
+
+	info, err := f.Probe(ctx, p.InputPath)
+	if err != nil {
+		return splitPlan{}, err
+	}
+	stream, err := selectAudioStream(info, 0, "")
+	if err != nil {
+		return splitPlan{}, err
+	}
+	if source := sourceAudioCodec(stream.CodecName); source == "" || !containsCodec(spec.audioCodecs, source) {
+		return splitPlan{}, fmt.Errorf("%w: source audio codec %q cannot be copied into format %q, use SplitModeReencode",
+			ErrInvalidParams, stream.CodecName, format)
+	}
+	plan.audio = stream
+	return plan, nil
+}
+
+// keyFrameStart 返回直接复制视频时第一个分段在输入文件中的实际开始时间
+// 输入端定位时ffmpeg从开始时间之前最近的关键帧开始复制，
+// 通过ffprobe定位到开始时间并读取第一个视频包即可得到该关键帧的时间；无法获取时返回开始时间本身
+func (f *FFmpeg) keyFrameStart(ctx context.Context, p *SplitVideoParams) time.Duration {
+	trim := p.timeRange()
+	if trim.start == 0 || trim.accurate || p.InputPath == "" {
+		return trim.start
+	}
+
+	var stdout bytes.Buffer
+	err := f.run(ctx, &runOptions{
+		binary: f.ffprobeBinary(),
+		args: []string{"-v", "error", "-select_streams", "v:0",
+			"-read_intervals", formatSeconds(trim.start) + "%+#1",
+			"-show_entries", "packet=pts_time", "-of", "csv=p=0", p.InputPath},
+		stdout: &stdout,
+	})
+	if err != nil {
+		f.logger().DebugContext(ctx, "failed to probe start key frame", "input", p.InputPath, "error", err)
+		return trim.start
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(stdout.String()), "\n")
+	seconds, err := strconv.ParseFloat(strings.Trim(line, ", "), 64)
+	if err != nil || seconds < 0 {
+		return trim.start
+	}
+	keyFrame := time.Duration(math.Round(seconds * float64(time.Second)))
+	return min(keyFrame, trim.start)
+}
+
+// planCuts 根据切分依据计算切分时长或切分点，按章节、场景或大小切分时需要先获取输入文件信息
+func (f *FFmpeg) planCuts(ctx context.Context, p *SplitVideoParams) (splitPlan, error) {
+	trim := p.timeRange()
+	switch {
+	case p.mode() == SplitModeScene:
+		scenes, err := f.detectScenes(ctx, p)
+		if err != nil {
+			return splitPlan{}, err
+		}
+		return splitPlan{cuts: sceneCuts(scenes, time.Duration(p.SegmentTime)*time.Second)}, nil
+
+	case len(p.CutPoints) > 0:
+		return splitPlan{cuts: relativeCuts(p.CutPoints, trim)}, nil
+
+	case p.ByChapter:
+		info, err := f.Probe(ctx, p.InputPath)
+		if err != nil {
+			return splitPlan{}, err
+		}
+		if len(info.Chapters) == 0 {
+			return splitPlan{}, fmt.Errorf("%w: %s", ErrNoChapters, p.InputPath)
+		}
+		starts := make([]time.Duration, 0, len(info.Chapters))
+		for _, chapter := range info.Chapters {
+			starts = append(starts, chapter.Start)
+		}
+		return splitPlan{cuts: relativeCuts(starts, trim)}, nil
+
+	case p.MaxSize > 0:
+		info, err := f.Probe(ctx, p.InputPath)
+		if err != nil {
+			return splitPlan{}, err
+		}
+		interval, err := maxSizeInterval(p.MaxSize, info.Format)
+		if err != nil {
+			return splitPlan{}, err
+		}
+		return splitPlan{interval: interval}, nil
+
+	default:
+		return splitPlan{interval: time.Duration(p.SegmentTime) * time.Second}, nil
+	}
+}
+
+// relativeCuts 将相对于输入文件开头的时间点转换为相对于裁剪范围开始时间的切分点
+// 范围之外以及与开始时间重合的时间点会被丢弃，结果按时间排序
+func relativeCuts(points []time.Duration, trim timeRange) []time.Duration {
+	sorted := append([]time.Duration(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var cuts []time.Duration
+	length := trim.length()
+	for _, point := range sorted {
+		cut := point - trim.start
+		if cut <= 0 || (length > 0 && cut >= length) {
+			continue
+		}
+		if len(cuts) > 0 && cuts[len(cuts)-1] == cut {
+			continue
+		}
+		cuts = append(cuts, cut)
+	}
+	return cuts
+}
+
+// maxSizeInterval 根据输入文件的平均码率估算不超过maxSize字节的分段时长
+func maxSizeInterval(maxSize int64, format FormatInfo) (time.Duration, error) {
+	bitRate := format.BitRate
+	if bitRate <= 0 && format.Size > 0 && format.Duration > 0 {
+		bitRate = int64(float64(format.Size*8) / format.Duration.Seconds())
+	}
+	if bitRate <= 0 {
+		return 0, fmt.Errorf("cannot split by size: unknown bit rate of %s", format.Filename)
+	}
+
+	seconds := float64(maxSize*8) / float64(bitRate) * maxSizeHeadroom
+	interval := time.Duration(seconds * float64(time.Second)).Truncate(time.Millisecond)
+	if interval < time.Second {
+		return 0, fmt.Errorf("%w: max size %d bytes is too small for bit rate %d bit/s", ErrInvalidParams, maxSize, bitRate)
+	}
+	return interval, nil
+}
+
+// shrinkInterval 根据超出上限的分段大小按比例缩短分段时长
+func shrinkInterval(interval time.Duration, size, maxSize int64) time.Duration {
+	seconds := interval.Seconds() * float64(maxSize) / float64(size) * maxSizeHeadroom
+	return time.Duration(seconds * float64(time.Second)).Truncate(time.Millisecond)
+}
+
+// largestSegment 返回文件最大的分段
+func largestSegment(segments []Segment) Segment {
+	var largest Segment
+	for _, segment := range segments {
+		if segment.Size > largest.Size {
+			largest = segment
+		}
+	}
+	return largest
+}
+
+// splitSegments 按切分方式运行一次segment muxer，将分段写入dir，返回生成的分段
+// 按MaxSize切分时本次输出可能被丢弃，因此结束时不报告完成，由调用方在接受输出后报告
+func (f *FFmpeg) splitSegments(ctx context.Context, p *SplitVideoParams, plan splitPlan, total time.Duration, dir string) ([]Segment, error) {
+	trim := p.timeRange()
+	format := p.format()
+	outputPattern := filepath.Join(dir, p.OutputPrefix+"%03d."+string(format))
+
+	// segment muxer将每个分段的文件名及开始、结束时间写入CSV列表，
+	// 以此作为本次运行生成的分段的唯一来源
+	listFile, err := os.CreateTemp("", "linker-ffmpeg-segments-*.csv")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create segment list: %w", err)
+	}
+	listFile.Close()
+	defer os.Remove(listFile.Name())
+
+	args := trim.wrapInput(inputURL(p.InputPath, p.Input))
+	args = append(args, splitModeArgs(p.mode(), format, plan)...)
+	args = append(args, "-f", "segment", "-segment_format", containerSpecs[format].muxer, "-reset_timestamps", "1",
+		"-segment_list", listFile.Name(), "-segment_list_type", "csv",
+		outputPattern)
+	err = f.run(ctx, &runOptions{
+		binary:   f.FFmpegPath,
+		args:     args,
+		stdin:    p.Input,
+		progress: true,
+		partial:  true,
+		total:    total,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 解析分段列表，时间换算为输入文件中的时间
+	return readSegmentList(listFile.Name(), dir, plan.start)
+}
+
+// acceptSegments 获取分段的实际时长后将分段文件移动到输出目录outputDir，返回移动后的分段
+func (f *FFmpeg) acceptSegments(ctx context.Context, segments []Segment, outputDir string) ([]Segment, error) {
+	// 分段列表中的时间只是segment muxer的记录，以ffprobe获取的实际时长为准
+	if err := f.probeSegmentTimes(ctx, segments); err != nil {
+		return nil, err
+	}
+	for i := range segments {
+		path := filepath.Join(outputDir, filepath.Base(segments[i].Path))
+		if err := os.Rename(segments[i].Path, path); err != nil {
+			return nil, fmt.Errorf("failed to move segment: %w", err)
+		}
+		segments[i].Path = path
+	}
+	return segments, nil
+}
+
+// probeSegmentTimes 通过ffprobe获取每个分段文件的实际时长，并据此重新计算开始和结束时间
+// 第一个分段的开始时间沿用分段列表中的时间，之后每个分段紧接上一个分段的结束时间；
+// 无法获取时长的分段沿用分段列表中的时长；ctx被取消或超时时返回ctx的错误
+func (f *FFmpeg) probeSegmentTimes(ctx context.Context, segments []Segment) error {
+	for i := range segments {
+		segment := &segments[i]
+		if i > 0 {
+			segment.Start = segments[i-1].End
+		}
+		if duration, ok := f.probeSegmentDuration(ctx, segment.Path); ok {
+			segment.Duration = duration
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		segment.End = segment.Start + segment.Duration
+	}
+	return nil
+}
+
+// probeSegmentDuration 通过ffprobe获取分段文件容器的时长
+func (f *FFmpeg) probeSegmentDuration(ctx context.Context, path string) (time.Duration, bool) {
+	var stdout bytes.Buffer
+	err := f.run(ctx, &runOptions{
+		binary: f.ffprobeBinary(),
+		args: []string{"-v", "error", "-show_entries", "format=duration",
+			"-of", "default=noprint_wrappers=1:nokey=1", path},
+		stdout: &stdout,
+	})
+	if err != nil {
+		f.logger().DebugContext(ctx, "failed to probe segment duration", "segment", path, "error", err)
+		return 0, false
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(stdout.String()), 64)
+	if err != nil || seconds <= 0 {
+		return 0, false
+	}
+	return time.Duration(math.Round(seconds * float64(time.Second))), true
+}
+
+// splitCodecArgs 返回分段时使用的编码参数
+// 重新编码时使用格式的默认编码器；音频格式只输出音频流
+func splitCodecArgs(reencode bool, format Container) []string {
+	spec := containerSpecs[format]
+	var args []string
+	if len(spec.videoCodecs) == 0 {
+		args = append(args, "-vn", "-sn", "-dn")
+	}
+	if !reencode {
+		return append(args, "-c", "copy")
+	}
+	if len(spec.videoCodecs) > 0 {
+		args = append(args, "-c:v", videoEncoders[spec.videoCodecs[0]])
+	}
+	return append(args, "-c:a", audioEncoders[spec.audioCodecs[0]])
+}
+
+// splitModeArgs 根据分段方式和切分方式构建编码及segment muxer的切分参数
+// 重新编码视频时在每个切分点强制插入关键帧，segment muxer即可在该处精确切分；
+// 直接复制时segment muxer只能在切分点之后的第一个关键帧处切分
+func splitModeArgs(mode SplitMode, format Container, plan splitPlan) []string {
+	reencode := mode == SplitModeReencode || mode == SplitModeScene
+	var args []string
+	if plan.audio != nil {
+		args = append(args, "-map", fmt.Sprintf("0:%d", plan.audio.Index))
+	}
+	args = append(args, splitCodecArgs(reencode, format)...)
+	forceKeyFrames := reencode && len(containerSpecs[format].videoCodecs) > 0
+
+	if plan.interval > 0 {
+		interval := formatSeconds(plan.interval)
+		if forceKeyFrames {
+			args = append(args, "-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%s)", interval))
+		}
+		return append(args, "-segment_time", interval)
+	}
+
+	if len(plan.cuts) == 0 {
+		// 没有切分点时整个范围输出为一个分段
+		return append(args, "-segment_time", strconv.Itoa(math.MaxInt32))
+	}
+	times := make([]string, len(plan.cuts))
+	for i, cut := range plan.cuts {
+		times[i] = formatSeconds(cut)
+	}
+	list := strings.Join(times, ",")
+	if forceKeyFrames {
+		args = append(args, "-force_key_frames", list)
+	}
+	return append(args, "-segment_times", list)
+}
+
+// detectScenes 通过select和showinfo过滤器检测场景切换的时间点
+// 返回的时间相对于裁剪范围的开始时间
+func (f *FFmpeg) detectScenes(ctx context.Context, p *SplitVideoParams) ([]time.Duration, error) {
+	// 检测只需解码，使用输入端快速定位即可，时间轴与分段时一致
+	trim := p.timeRange()
+	trim.accurate = false
+
+	var scenes []time.Duration
+	args := append(trim.wrapInput(p.InputPath),
+		"-an", "-sn", "-dn",
+		"-vf", fmt.Sprintf("select='gt(scene,%s)',showinfo", strconv.FormatFloat(p.sceneThreshold(), 'f', -1, 64)),
+		"-f", "null", "-")
+	err := f.run(ctx, &runOptions{
+		binary: f.FFmpegPath,
+		args:   args,
+		stderr: func(line string) bool {
+			if t, ok := parseShowinfoTime(line); ok {
+				scenes = append(scenes, t)
+			}
+			return filterInfoLine(line)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(scenes, func(i, j int) bool { return scenes[i] < scenes[j] })
+	return scenes, nil
+}
+
+// parseShowinfoTime 从showinfo过滤器的一行输出中解析帧的时间
+func parseShowinfoTime(line string) (time.Duration, bool) {
+	if !strings.Contains(line, "Parsed_showinfo") {
+		return 0, false
+	}
+	match := showinfoTimePattern.FindStringSubmatch(line)
+	if match == nil {
+		return 0, false
+	}
+	seconds, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(math.Round(seconds * float64(time.Second))), true
+}
+
+// sceneCuts 从场景切换时间点中选出切分点
+// 每个切分点都是距上一个切分点至少minLength之后的第一个场景切换
+func sceneCuts(scenes []time.Duration, minLength time.Duration) []time.Duration {
+	var cuts []time.Duration
+	var last time.Duration
+	for _, scene := range scenes {
+		if scene-last >= minLength {
+			cuts = append(cuts, scene)
+			last = scene
+		}
+	}
+	return cuts
+}
+
+// readSegmentList 解析segment muxer输出的CSV分段列表
+// 每行格式为"文件名,开始时间,结束时间"，时间单位为秒；
+// offset为第一个分段在输入文件中的开始时间，列表中的时间按相对于第一个分段开始时间的偏移换算
+func readSegmentList(listPath, dir string, offset time.Duration) ([]Segment, error) {
+	file, err := os.Open(listPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read segment list: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 3
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse segment list: %w", err)
+	}
+
+	segments := make([]Segment, 0, len(records))
+	var first float64
+	for i, record := range records {
+		start, err1 := strconv.ParseFloat(record[1], 64)
+		end, err2 := strconv.ParseFloat(record[2], 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("failed to parse segment list: invalid times in line %d", i+1)
+		}
+		// 直接复制时第一个分段从开始时间之前的关键帧开始，列表中的时间可能不从0开始
+		if i == 0 {
+			first = start
+		}
+
+		segment := Segment{
+			Path:  filepath.Join(dir, record[0]),
+			Index: i,
+			Start: offset + time.Duration((start-first)*float64(time.Second)),
+			End:   offset + time.Duration((end-first)*float64(time.Second)),
+		}
+		segment.Duration = segment.End - segment.Start
+		if stat, err := os.Stat(segment.Path); err == nil {
+			segment.Size = stat.Size()
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
@@ -0,0 +1,503 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSplitModeArgs 测试不同分段方式和切分方式生成的编码及切分参数
+func TestSplitModeArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   SplitMode
+		format Container
+		plan   splitPlan
+		want   []string
+	}{
+		{"copy interval", SplitModeCopy, ContainerMP4, splitPlan{interval: 10 * time.Second}, []string{"-c", "copy", "-segment_time", "10"}},
+		{"reencode interval", SplitModeReencode, ContainerMP4, splitPlan{interval: 10 * time.Second},
+			[]string{"-c:v", "libx264", "-c:a", "aac", "-force_key_frames", "expr:gte(t,n_forced*10)", "-segment_time", "10"}},
+		{"copy cut points", SplitModeCopy, ContainerMKV, splitPlan{cuts: []time.Duration{90 * time.Second, 150 * time.Second}},
+			[]string{"-c", "copy", "-segment_times", "90,150"}},
+		{"scene cut points", SplitModeScene, ContainerMP4, splitPlan{cuts: []time.Duration{12500 * time.Millisecond, 31 * time.Second}},
+			[]string{"-c:v", "libx264", "-c:a", "aac", "-force_key_frames", "12.5,31", "-segment_times", "12.5,31"}},
+		{"no cut points", SplitModeScene, ContainerMP4, splitPlan{},
+			[]string{"-c:v", "libx264", "-c:a", "aac", "-segment_time", "2147483647"}},
+		{"webm reencode", SplitModeReencode, ContainerWebM, splitPlan{interval: 5 * time.Second},
+			[]string{"-c:v", "libvpx-vp9", "-c:a", "libopus", "-force_key_frames", "expr:gte(t,n_forced*5)", "-segment_time", "5"}},
+		{"audio copy", SplitModeCopy, ContainerMP3, splitPlan{interval: 10 * time.Minute, audio: &StreamInfo{Index: 2}},
+			[]string{"-map", "0:2", "-vn", "-sn", "-dn", "-c", "copy", "-segment_time", "600"}},
+		{"audio reencode", SplitModeReencode, ContainerOpus, splitPlan{interval: 10 * time.Minute},
+			[]string{"-vn", "-sn", "-dn", "-c:a", "libopus", "-segment_time", "600"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitModeArgs(tt.mode, tt.format, tt.plan); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitModeArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRelativeCuts 测试切分点按裁剪范围转换、排序并去掉范围之外的时间点
+func TestRelativeCuts(t *testing.T) {
+	points := []time.Duration{50 * time.Second, 10 * time.Second, 30 * time.Second, 30 * time.Second, 90 * time.Second}
+	trim := timeRange{start: 20 * time.Second, end: 80 * time.Second}
+	want := []time.Duration{10 * time.Second, 30 * time.Second}
+	if got := relativeCuts(points, trim); !reflect.DeepEqual(got, want) {
+		t.Errorf("relativeCuts() = %v, want %v", got, want)
+	}
+}
+
+// TestMaxSizeInterval 测试根据码率估算分段时长
+func TestMaxSizeInterval(t *testing.T) {
+	// 8 Mbit/s，10 MB的分段约为10秒，预留余量后为9秒
+	got, err := maxSizeInterval(10_000_000, FormatInfo{BitRate: 8_000_000})
+	if err != nil || got != 9*time.Second {
+		t.Errorf("maxSizeInterval() = %v, %v, want 9s", got, err)
+	}
+
+	// 没有码率时根据文件大小和时长计算
+	got, err = maxSizeInterval(1_000_000, FormatInfo{Size: 10_000_000, Duration: 100 * time.Second})
+	if err != nil || got != 9*time.Second {
+		t.Errorf("maxSizeInterval() = %v, %v, want 9s", got, err)
+	}
+
+	if _, err := maxSizeInterval(1000, FormatInfo{BitRate: 8_000_000}); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("Expected ErrInvalidParams for a too small max size, got %v", err)
+	}
+}
+
+// TestSceneCuts 测试只在距上一个切分点足够远的场景切换处切分
+func TestSceneCuts(t *testing.T) {
+	scenes := []time.Duration{3 * time.Second, 11 * time.Second, 14 * time.Second, 19 * time.Second, 22 * time.Second, 40 * time.Second}
+	want := []time.Duration{11 * time.Second, 22 * time.Second, 40 * time.Second}
+	if got := sceneCuts(scenes, 10*time.Second); !reflect.DeepEqual(got, want) {
+		t.Errorf("sceneCuts() = %v, want %v", got, want)
+	}
+}
+
+// TestParseShowinfoTime 测试从showinfo输出中解析帧时间
+func TestParseShowinfoTime(t *testing.T) {
+	line := "[Parsed_showinfo_1 @ 0x7f8c] n:   3 pts: 376832 pts_time:29.44   duration:    512 fmt:yuv420p"
+	got, ok := parseShowinfoTime(line)
+	if !ok || got != 29440*time.Millisecond {
+		t.Errorf("parseShowinfoTime() = %v, %v, want 29.44s", got, ok)
+	}
+
+	if _, ok := parseShowinfoTime("frame=  100 fps=25 q=-0.0 size=N/A time=00:00:04.00"); ok {
+		t.Error("Expected non-showinfo line to be ignored")
+	}
+}
+
+// TestSplitVideoParamsValidate 测试无效的分段参数被拒绝
+func TestSplitVideoParamsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		params SplitVideoParams
+	}{
+		{"zero segment time", SplitVideoParams{InputPath: "in.mp4", OutputDir: "out"}},
+		{"unknown mode", SplitVideoParams{InputPath: "in.mp4", OutputDir: "out", SegmentTime: 10, Mode: "fast"}},
+		{"threshold out of range", SplitVideoParams{InputPath: "in.mp4", OutputDir: "out", SegmentTime: 10, Mode: SplitModeScene, SceneThreshold: 1.5}},
+		{"unknown format", SplitVideoParams{InputPath: "in.mp4", OutputDir: "out", SegmentTime: 10, Format: "avi"}},
+		{"scene in audio format", SplitVideoParams{InputPath: "in.mp4", OutputDir: "out", SegmentTime: 10, Format: ContainerM4A, Mode: SplitModeScene}},
+		{"multiple strategies", SplitVideoParams{InputPath: "in.mp4", OutputDir: "out", SegmentTime: 10, ByChapter: true}},
+		{"negative cut point", SplitVideoParams{InputPath: "in.mp4", OutputDir: "out", CutPoints: []time.Duration{-time.Second}}},
+		{"scene without segment time", SplitVideoParams{InputPath: "in.mp4", OutputDir: "out", MaxSize: 1 << 20, Mode: SplitModeScene}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(); !errors.Is(err, ErrInvalidParams) {
+				t.Errorf("Validate() error = %v, want ErrInvalidParams", err)
+			}
+		})
+	}
+}
+
+// TestReadSegmentList 测试解析segment muxer输出的CSV分段列表
+func TestReadSegmentList(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "seg_000.mp4"), []byte("12345"), 0644); err != nil {
+		t.Fatalf("Failed to write segment: %v", err)
+	}
+
+	listPath := filepath.Join(t.TempDir(), "list.csv")
+	list := "seg_000.mp4,0.000000,10.010000\n\"seg,001.mp4\",10.010000,18.500000\n"
+	if err := os.WriteFile(listPath, []byte(list), 0644); err != nil {
+		t.Fatalf("Failed to write segment list: %v", err)
+	}
+
+	segments, err := readSegmentList(listPath, dir, 2*time.Second)
+	if err != nil {
+		t.Fatalf("readSegmentList() error = %v", err)
+	}
+
+	want := []Segment{
+		{Path: filepath.Join(dir, "seg_000.mp4"), Index: 0, Start: 2 * time.Second, End: 12010 * time.Millisecond, Duration: 10010 * time.Millisecond, Size: 5},
+		{Path: filepath.Join(dir, "seg,001.mp4"), Index: 1, Start: 12010 * time.Millisecond, End: 20500 * time.Millisecond, Duration: 8490 * time.Millisecond},
+	}
+	if !reflect.DeepEqual(segments, want) {
+		t.Errorf("readSegmentList() =\n%+v\nwant\n%+v", segments, want)
+	}
+}
+
+// TestReadSegmentListShifted 测试列表中的时间不从0开始时按相对于第一个分段的偏移换算
+func TestReadSegmentListShifted(t *testing.T) {
+	listPath := filepath.Join(t.TempDir(), "list.csv")
+	list := "seg_000.mp4,-1.500000,8.500000\nseg_001.mp4,8.500000,18.500000\n"
+	if err := os.WriteFile(listPath, []byte(list), 0644); err != nil {
+		t.Fatalf("Failed to write segment list: %v", err)
+	}
+
+	segments, err := readSegmentList(listPath, t.TempDir(), 8500*time.Millisecond)
+	if err != nil {
+		t.Fatalf("readSegmentList() error = %v", err)
+	}
+	if len(segments) != 2 || segments[0].Start != 8500*time.Millisecond || segments[1].Start != 18500*time.Millisecond ||
+		segments[1].End != 28500*time.Millisecond {
+		t.Errorf("Unexpected segments: %+v", segments)
+	}
+}
+
+// TestKeyFrameStart 测试直接复制时以开始时间之前的关键帧作为第一个分段的开始时间
+func TestKeyFrameStart(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string // ffprobe的输出
+		params SplitVideoParams
+		want   time.Duration
+	}{
+		{"key frame before start", "8.341667,\n", SplitVideoParams{InputPath: "input.mp4", TimeRange: TimeRange{Start: 10 * time.Second}}, 8341667 * time.Microsecond},
+		{"unparsable output", "{}", SplitVideoParams{InputPath: "input.mp4", TimeRange: TimeRange{Start: 10 * time.Second}}, 10 * time.Second},
+		{"accurate seek", "8.341667", SplitVideoParams{InputPath: "input.mp4", TimeRange: TimeRange{Start: 10 * time.Second, AccurateSeek: true}}, 10 * time.Second},
+		{"reader input", "8.341667", SplitVideoParams{Input: strings.NewReader(""), TimeRange: TimeRange{Start: 10 * time.Second}}, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ffmpeg := &FFmpeg{FFprobePath: writeFakeBinary(t, "ffprobe", `printf '`+tt.output+`'`)}
+			if got := ffmpeg.keyFrameStart(context.Background(), &tt.params); got != tt.want {
+				t.Errorf("keyFrameStart() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSplitVideoSceneSegments 测试按场景切分并从分段列表中返回本次生成的分段，时长以ffprobe获取的为准
+func TestSplitVideoSceneSegments(t *testing.T) {
+	outputDir := t.TempDir()
+	argsFile := filepath.Join(t.TempDir(), "args")
+
+	// 检测场景时输出showinfo日志，分段时生成两个分段文件
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `case "$*" in
+*showinfo*)
+	echo "[Parsed_showinfo_1 @ 0x1] n:   0 pts:   1000 pts_time:4 duration: 1" >&2
+	echo "[Parsed_showinfo_1 @ 0x1] n:   1 pts:   1000 pts_time:12.5 duration: 1" >&2
+	;;
+*)
+	echo "$@" > "`+argsFile+`"
+	prev=""
+	for arg; do
+		[ "$prev" = "-segment_list" ] && list="$arg"
+		prev="$arg"
+	done
+	dir=$(dirname "$arg")
+	echo a > "$dir/scene_000.mp4"
+	echo bb > "$dir/scene_001.mp4"
+	printf 'scene_000.mp4,0.000000,12.500000\nscene_001.mp4,12.500000,30.000000\n' > "$list"
+	;;
+esac
+`)
+
+	// 之前运行留下的同前缀文件不应出现在结果中
+	if err := os.WriteFile(filepath.Join(outputDir, "scene_old.mp4"), []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to write stale segment: %v", err)
+	}
+
+	// 分段的实际时长与分段列表中的记录略有不同
+	fakeFFprobe := writeFakeBinary(t, "ffprobe", `case "$*" in
+*scene_000*) echo 12.48 ;;
+*scene_001*) echo 17.52 ;;
+*) exit 1 ;;
+esac
+`)
+
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, fakeFFprobe, nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	segments, err := ffmpeg.SplitVideo(&SplitVideoParams{
+		InputPath:    "input.mp4",
+		OutputDir:    outputDir,
+		SegmentTime:  10,
+		OutputPrefix: "scene_",
+		Mode:         SplitModeScene,
+		TimeRange:    TimeRange{Start: 5 * time.Second},
+	})
+	if err != nil {
+		t.Fatalf("SplitVideo failed: %v", err)
+	}
+
+	data, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("Failed to read recorded arguments: %v", err)
+	}
+	if !strings.Contains(string(data), "-segment_times 12.5") {
+		t.Errorf("Expected cut at the scene change after 10s, got args: %s", data)
+	}
+
+	want := []Segment{
+		{Path: filepath.Join(outputDir, "scene_000.mp4"), Index: 0, Start: 5 * time.Second, End: 17480 * time.Millisecond, Duration: 12480 * time.Millisecond, Size: 2},
+		{Path: filepath.Join(outputDir, "scene_001.mp4"), Index: 1, Start: 17480 * time.Millisecond, End: 35 * time.Second, Duration: 17520 * time.Millisecond, Size: 3},
+	}
+	if !reflect.DeepEqual(segments, want) {
+		t.Errorf("SplitVideo() =\n%+v\nwant\n%+v", segments, want)
+	}
+}
+
+// TestSplitVideoByChapter 测试按章节切分时在每个章节的开始时间切分
+func TestSplitVideoByChapter(t *testing.T) {
+	outputDir := t.TempDir()
+	argsFile := filepath.Join(t.TempDir(), "args")
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `echo "$@" > "`+argsFile+`"
+prev=""
+for arg; do
+	[ "$prev" = "-segment_list" ] && list="$arg"
+	prev="$arg"
+done
+: > "$list"
+`)
+
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, writeFakeFFprobe(t), nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	if _, err := ffmpeg.SplitVideo(&SplitVideoParams{
+		InputPath:    "input.mp4",
+		OutputDir:    outputDir,
+		ByChapter:    true,
+		OutputPrefix: "chapter_",
+	}); err != nil {
+		t.Fatalf("SplitVideo failed: %v", err)
+	}
+
+	// 模拟的ffprobe返回两个章节，第二个章节从60秒开始
+	data, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("Failed to read recorded arguments: %v", err)
+	}
+	if !strings.Contains(string(data), "-c copy -segment_times 60 ") {
+		t.Errorf("Expected a cut at the second chapter, got args: %s", data)
+	}
+}
+
+// TestSplitVideoAudioCopy 测试直接复制到音频格式时检查源音频编码并只映射选中的音轨
+func TestSplitVideoAudioCopy(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Container
+		want   string // 期望的参数片段，为空表示应在启动ffmpeg前失败
+	}{
+		{"compatible codec", ContainerM4A, "-map 0:1 -vn -sn -dn -c copy "},
+		{"incompatible codec", ContainerMP3, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			argsFile := filepath.Join(t.TempDir(), "args")
+			fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `echo "$@" > "`+argsFile+`"
+prev=""
+for arg; do
+	[ "$prev" = "-segment_list" ] && list="$arg"
+	prev="$arg"
+done
+: > "$list"
+`)
+
+			ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, writeFakeFFprobe(t), nil)
+			if err != nil {
+				t.Fatalf("Failed to create FFmpeg instance: %v", err)
+			}
+
+			// 模拟的ffprobe返回aac音频流，默认音轨的序号为1
+			_, err = ffmpeg.SplitVideo(&SplitVideoParams{
+				InputPath:   "input.mp4",
+				OutputDir:   t.TempDir(),
+				SegmentTime: 600,
+				Format:      tt.format,
+			})
+			data, readErr := os.ReadFile(argsFile)
+			if tt.want == "" {
+				if !errors.Is(err, ErrInvalidParams) {
+					t.Errorf("SplitVideo() error = %v, want ErrInvalidParams", err)
+				}
+				if readErr == nil {
+					t.Errorf("Expected ffmpeg not to run, got args: %s", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SplitVideo failed: %v", err)
+			}
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("Expected %q in args, got: %s", tt.want, data)
+			}
+		})
+	}
+}
+
+// TestShrinkInterval 测试按超出的比例缩短分段时长
+func TestShrinkInterval(t *testing.T) {
+	if got := shrinkInterval(10*time.Second, 12_000_000, 10_000_000); got != 7500*time.Millisecond {
+		t.Errorf("shrinkInterval() = %v, want 7.5s", got)
+	}
+}
+
+// TestSplitVideoMaxSize 测试按大小切分时有分段超出MaxSize则缩短分段时长重新切分，
+// 被丢弃的输出不报告完成，也不会覆盖输出目录中已有的文件
+func TestSplitVideoMaxSize(t *testing.T) {
+	tests := []struct {
+		name  string
+		sizes []string // 每次运行生成的分段大小
+		runs  int
+		err   error
+	}{
+		{"re-split oversized segment", []string{"1000000 1000000 1000000 4400000", "3000000 3000000 500000"}, 2, nil},
+		{"still too large", []string{"4400000", "4400000", "4400000"}, maxSizeAttempts, ErrSegmentTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := t.TempDir()
+			workDir := t.TempDir()
+			argsFile := filepath.Join(workDir, "args")
+
+			// 每次运行按运行次数生成不同大小的分段，并写出分段列表
+			fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `echo "$@" >> "`+argsFile+`"
+run=$(($(cat "`+workDir+`/count" 2>/dev/null || echo 0) + 1))
+echo $run > "`+workDir+`/count"
+prev=""
+for arg; do
+	[ "$prev" = "-segment_list" ] && list="$arg"
+	prev="$arg"
+done
+dir=$(dirname "$arg")
+case $run in
+1) sizes="`+tt.sizes[0]+`" ;;
+2) sizes="`+tt.sizes[1]+`" ;;
+*) sizes="`+tt.sizes[len(tt.sizes)-1]+`" ;;
+esac
+i=0
+for size in $sizes; do
+	name=$(printf 'part_%03d.mp4' $i)
+	head -c $size /dev/zero > "$dir/$name"
+	echo "$name,$i,$((i + 1))" >> "$list"
+	i=$((i + 1))
+done
+`)
+
+			// 第一次切分生成的part_003.mp4被丢弃，不应覆盖之前运行留下的同名文件
+			stalePath := filepath.Join(outputDir, "part_003.mp4")
+			if err := os.WriteFile(stalePath, []byte("old"), 0644); err != nil {
+				t.Fatalf("Failed to write stale segment: %v", err)
+			}
+
+			completed := 0
+			ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, writeFakeFFprobe(t), func(progress *Progress) {
+				if progress.Status == "completed" {
+					completed++
+				}
+			})
+			if err != nil {
+				t.Fatalf("Failed to create FFmpeg instance: %v", err)
+			}
+
+			segments, err := ffmpeg.SplitVideo(&SplitVideoParams{
+				InputPath:    "input.mp4",
+				OutputDir:    outputDir,
+				MaxSize:      4_000_000,
+				OutputPrefix: "part_",
+			})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("SplitVideo() error = %v, want %v", err, tt.err)
+			}
+
+			// 每次重新切分都按超出的比例缩短分段时长
+			data, err := os.ReadFile(argsFile)
+			if err != nil {
+				t.Fatalf("Failed to read recorded arguments: %v", err)
+			}
+			runs := strings.Split(strings.TrimSpace(string(data)), "\n")
+			if len(runs) != tt.runs {
+				t.Fatalf("Expected %d runs, got %d", tt.runs, len(runs))
+			}
+			interval, _ := maxSizeInterval(4_000_000, FormatInfo{BitRate: 4648684})
+			for i, args := range runs {
+				if !strings.Contains(args, "-segment_time "+formatSeconds(interval)+" ") {
+					t.Errorf("Run %d: expected segment time %v, got args: %s", i+1, interval, args)
+				}
+				interval = shrinkInterval(interval, 4_400_000, 4_000_000)
+			}
+
+			wantCompleted := 1
+			if tt.err != nil {
+				wantCompleted = 0
+			}
+			if completed != wantCompleted {
+				t.Errorf("Expected %d completed progress callbacks, got %d", wantCompleted, completed)
+			}
+
+			if data, err := os.ReadFile(stalePath); err != nil || string(data) != "old" {
+				t.Errorf("Expected pre-existing file to be kept, got %d bytes, %v", len(data), err)
+			}
+			files, _ := filepath.Glob(filepath.Join(outputDir, "part_*"))
+			entries, _ := os.ReadDir(outputDir)
+			if len(entries) != len(files) {
+				t.Errorf("Expected temporary split directories to be removed, got %v", entries)
+			}
+			if tt.err != nil {
+				if segments != nil || len(files) != 1 {
+					t.Errorf("Expected no segments left after failure, got %v and files %v", segments, files)
+				}
+				return
+			}
+			if len(segments) != 3 || len(files) != 4 {
+				t.Fatalf("Expected the 3 segments of the second run, got %+v", segments)
+			}
+			for _, segment := range segments {
+				if segment.Size > 4_000_000 {
+					t.Errorf("Segment %s is %d bytes, exceeds max size", segment.Path, segment.Size)
+				}
+			}
+		})
+	}
+}
+
+// TestSplitVideoParamsFormat 测试分段格式默认沿用输入文件的容器格式
+func TestSplitVideoParamsFormat(t *testing.T) {
+	tests := []struct {
+		params SplitVideoParams
+		want   Container
+	}{
+		{SplitVideoParams{InputPath: "movie.MKV"}, ContainerMKV},
+		{SplitVideoParams{InputPath: "podcast.mp3"}, ContainerMP3},
+		{SplitVideoParams{InputPath: "legacy.avi"}, ContainerMP4},
+		{SplitVideoParams{InputPath: "podcast.mp3", Format: ContainerOpus}, ContainerOpus},
+	}
+
+	for _, tt := range tests {
+		if got := tt.params.format(); got != tt.want {
+			t.Errorf("format() for %s = %q, want %q", tt.params.InputPath, got, tt.want)
+		}
+	}
+}
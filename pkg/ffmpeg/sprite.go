@@ -0,0 +1,218 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSpriteColumns = 5   // 精灵图默认列数
+	defaultSpriteRows    = 5   // 精灵图默认行数
+	defaultSpriteWidth   = 160 // 缩略图默认宽度
+)
+
+// spriteWorkDirPattern 生成精灵图时使用的临时目录名，位于输出目录下以便直接重命名到输出目录
+const spriteWorkDirPattern = ".linker-ffmpeg-sprite-*"
+
+// columns 返回实际使用的列数
+func (p *SpriteSheetParams) columns() int {
+	if p.Columns == 0 {
+		return defaultSpriteColumns
+	}
+	return p.Columns
+}
+
+// rows 返回实际使用的行数
+func (p *SpriteSheetParams) rows() int {
+	if p.Rows == 0 {
+		return defaultSpriteRows
+	}
+	return p.Rows
+}
+
+// keyFrames 转换为按间隔采样的ExtractKeyFramesParams，复用其校验和选帧过滤器
+func (p *SpriteSheetParams) keyFrames() *ExtractKeyFramesParams {
+	width := p.Width
+	if width == 0 {
+		width = defaultSpriteWidth
+	}
+	return &ExtractKeyFramesParams{
+		InputPath:     p.InputPath,
+		Input:         p.Input,
+		OutputDir:     p.OutputDir,
+		Mode:          KeyFrameModeInterval,
+		FrameInterval: p.FrameInterval,
+		OutputPrefix:  p.OutputPrefix,
+		Format:        p.Format,
+		Quality:       p.Quality,
+		MaxWidth:      width,
+		TimeRange:     p.TimeRange,
+	}
+}
+
+// Validate 校验精灵图参数
+// 返回值:
+//
+//	error: 参数无效时返回包装了ErrInvalidParams的错误
+func (p *SpriteSheetParams) Validate() error {
+	if p.Columns < 0 || p.Rows < 0 || p.Width < 0 {
+		return fmt.Errorf("%w: columns, rows and width must not be negative", ErrInvalidParams)
+	}
+	return p.keyFrames().Validate()
+}
+
+// spriteSheetArgs 构建生成精灵图的输入、过滤器及编码参数，不包含输出文件
+// showinfo位于tile之前，输出的是每张缩略图的时间和尺寸
+func spriteSheetArgs(p *SpriteSheetParams) []string {
+	keyFrames := p.keyFrames()
+	filters := append(frameFilters(keyFrames, 0), fmt.Sprintf("tile=%dx%d", p.columns(), p.rows()))
+
+	args := keyFrames.timeRange().wrapInput(inputURL(p.InputPath, p.Input))
+	args = append(args, "-vf", strings.Join(filters, ","), "-vsync", "vfr")
+	return append(args, imageEncodeArgs(keyFrames)...)
+}
+
+// spriteThumbnails 根据showinfo输出的缩略图计算每张缩略图所在的精灵图及区域
+// tile过滤器按行从左到右填充，最后一张精灵图可能未填满
+// 缩略图的结束时间为下一张缩略图的开始时间，最后一张为开始时间加采样间隔，但不超过rangeEnd（为0时不限制）
+// 精灵图先写入临时目录workDir，所在精灵图没有写出的缩略图会被丢弃；返回的路径位于输出目录
+func spriteThumbnails(frames []Frame, p *SpriteSheetParams, ext string, rangeEnd time.Duration, workDir string) ([]SpriteThumbnail, []string) {
+	perSheet := p.columns() * p.rows()
+	interval := time.Duration(p.FrameInterval) * time.Second
+
+	var thumbnails []SpriteThumbnail
+	var sheets []string
+	for i, frame := range frames {
+		name := fmt.Sprintf("%s%03d%s", p.OutputPrefix, i/perSheet+1, ext)
+		sheet := filepath.Join(p.OutputDir, name)
+		if i%perSheet == 0 {
+			if _, err := os.Stat(filepath.Join(workDir, name)); err != nil {
+				break
+			}
+			sheets = append(sheets, sheet)
+		}
+
+		end := frame.PTS + interval
+		if i+1 < len(frames) {
+			end = frames[i+1].PTS
+		}
+		if rangeEnd > 0 && end > rangeEnd {
+			end = rangeEnd
+		}
+		position := i % perSheet
+		thumbnails = append(thumbnails, SpriteThumbnail{
+			Sheet:  sheet,
+			Start:  frame.PTS,
+			End:    end,
+			X:      position % p.columns() * frame.Width,
+			Y:      position / p.columns() * frame.Height,
+			Width:  frame.Width,
+			Height: frame.Height,
+		})
+	}
+	return thumbnails, sheets
+}
+
+// formatVTTTime 将时间格式化为WebVTT的时间戳，如"01:02:03.456"
+func formatVTTTime(d time.Duration) string {
+	d = d.Round(time.Millisecond)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d",
+		d/time.Hour, d%time.Hour/time.Minute, d%time.Minute/time.Second, d%time.Second/time.Millisecond)
+}
+
+// spriteVTT 生成WebVTT缩略图轨道，每条cue的内容为精灵图地址加#xywh区域
+func spriteVTT(thumbnails []SpriteThumbnail, baseURL string) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for _, thumbnail := range thumbnails {
+		fmt.Fprintf(&b, "\n%s --> %s\n%s%s#xywh=%d,%d,%d,%d\n",
+			formatVTTTime(thumbnail.Start), formatVTTTime(thumbnail.End),
+			baseURL, filepath.Base(thumbnail.Sheet),
+			thumbnail.X, thumbnail.Y, thumbnail.Width, thumbnail.Height)
+	}
+	return b.String()
+}
+
+// GenerateSpriteSheet 按间隔采样缩略图，通过tile过滤器拼接为网格精灵图，并生成WebVTT缩略图轨道
+// 缩略图超过Columns*Rows时输出多张精灵图；ctx被取消或超时时会删除本次生成的精灵图
+// 参数:
+//
+//	ctx: 控制命令生命周期的上下文
+//	params: 精灵图参数配置
+//
+// 返回值:
+//
+//	*SpriteSheet: 精灵图和WebVTT文件路径，以及每张缩略图的时间范围和区域
+//	error: 如果生成失败，返回错误信息
+//
+// 示例:
+//
+//	sprite, err := ffmpeg.GenerateSpriteSheet(ctx, &ffmpeg.SpriteSheetParams{
+//	    InputPath:     "input.mp4",
+//	    OutputDir:     "/tmp/sprites",
+//	    OutputPrefix:  "sprite_",
+//	    FrameInterval: 5,
+//	    Columns:       10,
+//	    Rows:          10,
+//	})
+func (f *FFmpeg) GenerateSpriteSheet(ctx context.Context, params *SpriteSheetParams) (*SpriteSheet, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	keyFrames := params.keyFrames()
+	trim := keyFrames.timeRange()
+	ext := keyFrames.ext()
+
+	// 确保输出目录存在
+	if err := os.MkdirAll(params.OutputDir, 0755); err != nil {
+		return nil, err
+	}
+
+	// 精灵图先写入输出目录下的临时目录，完成后才移动到输出目录，
+	// 临时目录中的文件一定是本次生成的，之前运行留下的精灵图不会被误认为本次的结果，取消时也不会留下不完整的精灵图
+	workDir, err := os.MkdirTemp(params.OutputDir, spriteWorkDirPattern)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	outputPattern := filepath.Join(workDir, params.OutputPrefix+"%03d"+ext)
+	parser := &frameInfoParser{offset: trim.start}
+	err = f.run(ctx, &runOptions{
+		binary:   f.FFmpegPath,
+		args:     append(spriteSheetArgs(params), outputPattern),
+		stdin:    params.Input,
+		stderr:   parser.parseLine,
+		progress: true,
+		total:    f.rangeDuration(ctx, params.InputPath, trim),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var rangeEnd time.Duration
+	if length := trim.length(); length > 0 {
+		rangeEnd = trim.start + length
+	}
+	thumbnails, sheets := spriteThumbnails(parser.frames, params, ext, rangeEnd, workDir)
+	for _, sheet := range sheets {
+		if err := os.Rename(filepath.Join(workDir, filepath.Base(sheet)), sheet); err != nil {
+			return nil, fmt.Errorf("failed to move sprite sheet: %w", err)
+		}
+	}
+
+	vttPath := filepath.Join(params.OutputDir, params.OutputPrefix+"thumbnails.vtt")
+	if err := os.WriteFile(vttPath, []byte(spriteVTT(thumbnails, params.BaseURL)), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write thumbnail track: %w", err)
+	}
+
+	return &SpriteSheet{
+		Sheets:     sheets,
+		VTTPath:    vttPath,
+		Thumbnails: thumbnails,
+	}, nil
+}
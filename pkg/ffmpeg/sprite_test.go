@@ -0,0 +1,183 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSpriteSheetArgs 测试缩放和showinfo位于tile之前
+func TestSpriteSheetArgs(t *testing.T) {
+	params := &SpriteSheetParams{InputPath: "in.mp4", FrameInterval: 10, Columns: 4, Rows: 3, Quality: 75}
+	want := []string{
+		"-i", "in.mp4",
+		"-vf", "fps=1/10,scale='min(iw,160)':-1,showinfo,tile=4x3", "-vsync", "vfr",
+		"-c:v", "mjpeg", "-q:v", "9",
+	}
+	if got := spriteSheetArgs(params); !reflect.DeepEqual(got, want) {
+		t.Errorf("spriteSheetArgs() = %q, want %q", got, want)
+	}
+
+	// 默认5x5网格
+	if got := spriteSheetArgs(&SpriteSheetParams{InputPath: "in.mp4", FrameInterval: 1}); got[3] != "fps=1/1,scale='min(iw,160)':-1,showinfo,tile=5x5" {
+		t.Errorf("Expected default 5x5 grid, got %q", got[3])
+	}
+}
+
+// TestSpriteSheetParamsValidate 测试无效的精灵图参数被拒绝
+func TestSpriteSheetParamsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		params SpriteSheetParams
+	}{
+		{"missing output dir", SpriteSheetParams{InputPath: "in.mp4", FrameInterval: 5}},
+		{"missing interval", SpriteSheetParams{InputPath: "in.mp4", OutputDir: "out"}},
+		{"negative columns", SpriteSheetParams{InputPath: "in.mp4", OutputDir: "out", FrameInterval: 5, Columns: -1}},
+		{"png quality", SpriteSheetParams{InputPath: "in.mp4", OutputDir: "out", FrameInterval: 5, Format: ImageFormatPNG, Quality: 50}},
+		{"accurate seek", SpriteSheetParams{InputPath: "in.mp4", OutputDir: "out", FrameInterval: 5, TimeRange: TimeRange{AccurateSeek: true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(); !errors.Is(err, ErrInvalidParams) {
+				t.Errorf("Validate() error = %v, want ErrInvalidParams", err)
+			}
+		})
+	}
+}
+
+// TestFormatVTTTime 测试WebVTT时间戳格式
+func TestFormatVTTTime(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                       "00:00:00.000",
+		1500 * time.Millisecond: "00:00:01.500",
+		time.Hour + 2*time.Minute + 3456*time.Millisecond: "01:02:03.456",
+	}
+	for d, want := range tests {
+		if got := formatVTTTime(d); got != want {
+			t.Errorf("formatVTTTime(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+// TestGenerateSpriteSheet 测试缩略图按网格分配到多张精灵图，并生成对应的WebVTT
+func TestGenerateSpriteSheet(t *testing.T) {
+	outputDir := t.TempDir()
+
+	// 三张160x90的缩略图，2x1网格需要两张精灵图
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `echo "[Parsed_showinfo_2 @ 0x1] n:   0 pts:      0 pts_time:0   s:160x90 type:I" >&2
+echo "[Parsed_showinfo_2 @ 0x1] n:   1 pts:      5 pts_time:5   s:160x90 type:P" >&2
+echo "[Parsed_showinfo_2 @ 0x1] n:   2 pts:     10 pts_time:10  s:160x90 type:P" >&2
+for last; do :; done
+echo a > "$(dirname "$last")/sprite_001.jpg"
+echo b > "$(dirname "$last")/sprite_002.jpg"
+`)
+
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	sprite, err := ffmpeg.GenerateSpriteSheet(context.Background(), &SpriteSheetParams{
+		InputPath:     "input.mp4",
+		OutputDir:     outputDir,
+		OutputPrefix:  "sprite_",
+		FrameInterval: 5,
+		Columns:       2,
+		Rows:          1,
+		BaseURL:       "https://cdn.example.com/",
+		TimeRange:     TimeRange{Start: time.Minute, Duration: 12 * time.Second},
+	})
+	if err != nil {
+		t.Fatalf("GenerateSpriteSheet failed: %v", err)
+	}
+
+	sheet1 := filepath.Join(outputDir, "sprite_001.jpg")
+	sheet2 := filepath.Join(outputDir, "sprite_002.jpg")
+	want := &SpriteSheet{
+		Sheets:  []string{sheet1, sheet2},
+		VTTPath: filepath.Join(outputDir, "sprite_thumbnails.vtt"),
+		Thumbnails: []SpriteThumbnail{
+			{Sheet: sheet1, Start: 60 * time.Second, End: 65 * time.Second, X: 0, Y: 0, Width: 160, Height: 90},
+			{Sheet: sheet1, Start: 65 * time.Second, End: 70 * time.Second, X: 160, Y: 0, Width: 160, Height: 90},
+			{Sheet: sheet2, Start: 70 * time.Second, End: 72 * time.Second, X: 0, Y: 0, Width: 160, Height: 90},
+		},
+	}
+	if !reflect.DeepEqual(sprite, want) {
+		t.Errorf("GenerateSpriteSheet() =\n%+v\nwant\n%+v", sprite, want)
+	}
+
+	data, err := os.ReadFile(sprite.VTTPath)
+	if err != nil {
+		t.Fatalf("Failed to read thumbnail track: %v", err)
+	}
+	wantVTT := `WEBVTT
+
+00:01:00.000 --> 00:01:05.000
+https://cdn.example.com/sprite_001.jpg#xywh=0,0,160,90
+
+00:01:05.000 --> 00:01:10.000
+https://cdn.example.com/sprite_001.jpg#xywh=160,0,160,90
+
+00:01:10.000 --> 00:01:12.000
+https://cdn.example.com/sprite_002.jpg#xywh=0,0,160,90
+`
+	if string(data) != wantVTT {
+		t.Errorf("thumbnail track =\n%s\nwant\n%s", data, wantVTT)
+	}
+}
+
+// TestGenerateSpriteSheetStaleSheet 测试之前运行留下的精灵图不会被当作本次没有写出的精灵图
+func TestGenerateSpriteSheetStaleSheet(t *testing.T) {
+	outputDir := t.TempDir()
+	stalePath := filepath.Join(outputDir, "sprite_002.jpg")
+	if err := os.WriteFile(stalePath, []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to write stale sheet: %v", err)
+	}
+
+	// showinfo输出三张缩略图，但只写出了第一张精灵图
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `echo "[Parsed_showinfo_2 @ 0x1] n:   0 pts:      0 pts_time:0   s:160x90 type:I" >&2
+echo "[Parsed_showinfo_2 @ 0x1] n:   1 pts:      5 pts_time:5   s:160x90 type:P" >&2
+echo "[Parsed_showinfo_2 @ 0x1] n:   2 pts:     10 pts_time:10  s:160x90 type:P" >&2
+for last; do :; done
+echo a > "$(dirname "$last")/sprite_001.jpg"
+`)
+
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	sprite, err := ffmpeg.GenerateSpriteSheet(context.Background(), &SpriteSheetParams{
+		InputPath:     "input.mp4",
+		OutputDir:     outputDir,
+		OutputPrefix:  "sprite_",
+		FrameInterval: 5,
+		Columns:       2,
+		Rows:          1,
+	})
+	if err != nil {
+		t.Fatalf("GenerateSpriteSheet failed: %v", err)
+	}
+
+	sheet := filepath.Join(outputDir, "sprite_001.jpg")
+	if !reflect.DeepEqual(sprite.Sheets, []string{sheet}) || len(sprite.Thumbnails) != 2 {
+		t.Errorf("Expected only the sheet written by this run, got %+v", sprite)
+	}
+	if data, err := os.ReadFile(stalePath); err != nil || string(data) != "old" {
+		t.Errorf("Expected stale sheet to be kept, got %q, %v", data, err)
+	}
+	vtt, _ := os.ReadFile(sprite.VTTPath)
+	if strings.Contains(string(vtt), "sprite_002.jpg") {
+		t.Errorf("Stale sheet referenced in thumbnail track:\n%s", vtt)
+	}
+	entries, _ := os.ReadDir(outputDir)
+	if len(entries) != 3 {
+		t.Errorf("Expected the temporary directory to be removed, got %v", entries)
+	}
+}
@@ -0,0 +1,409 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// containerSpec 描述容器格式对应的muxer及支持的编码格式
+type containerSpec struct {
+	muxer       string       // ffmpeg muxer名称
+	videoCodecs []VideoCodec // 支持的视频编码，第一个为默认编码；为空表示不支持视频
+	audioCodecs []AudioCodec // 支持的音频编码，第一个为默认编码
+	faststart   bool         // 是否需要把moov移动到文件开头
+	seekable    bool         // 写入结束后需要回到文件开头更新信息，不能输出到管道
+}
+
+// containerSpecs 支持的输出容器格式
+var containerSpecs = map[Container]containerSpec{
+	ContainerMP4: {
+		muxer:       "mp4",
+		videoCodecs: []VideoCodec{VideoCodecH264, VideoCodecHEVC, VideoCodecAV1, VideoCodecVP9},
+		audioCodecs: []AudioCodec{AudioCodecAAC, AudioCodecMP3, AudioCodecOpus, AudioCodecFLAC},
+		faststart:   true,
+		seekable:    true,
+	},
+	ContainerMOV: {
+		muxer:       "mov",
+		videoCodecs: []VideoCodec{VideoCodecH264, VideoCodecHEVC},
+		audioCodecs: []AudioCodec{AudioCodecAAC, AudioCodecMP3, AudioCodecPCM},
+		faststart:   true,
+		seekable:    true,
+	},
+	ContainerMKV: {
+		muxer:       "matroska",
+		videoCodecs: []VideoCodec{VideoCodecH264, VideoCodecHEVC, VideoCodecVP9, VideoCodecAV1},
+		audioCodecs: []AudioCodec{AudioCodecOpus, AudioCodecAAC, AudioCodecMP3, AudioCodecFLAC, AudioCodecPCM},
+	},
+	ContainerWebM: {
+		muxer:       "webm",
+		videoCodecs: []VideoCodec{VideoCodecVP9, VideoCodecAV1},
+		audioCodecs: []AudioCodec{AudioCodecOpus},
+	},
+	ContainerMPEGTS: {
+		muxer:       "mpegts",
+		videoCodecs: []VideoCodec{VideoCodecH264, VideoCodecHEVC},
+		audioCodecs: []AudioCodec{AudioCodecAAC, AudioCodecMP3, AudioCodecOpus},
+	},
+	ContainerM4A:  {muxer: "ipod", audioCodecs: []AudioCodec{AudioCodecAAC}, faststart: true, seekable: true},
+	ContainerAAC:  {muxer: "adts", audioCodecs: []AudioCodec{AudioCodecAAC}},
+	ContainerMKA:  {muxer: "matroska", audioCodecs: []AudioCodec{AudioCodecOpus, AudioCodecAAC, AudioCodecMP3, AudioCodecFLAC, AudioCodecPCM}},
+	ContainerMP3:  {muxer: "mp3", audioCodecs: []AudioCodec{AudioCodecMP3}},
+	ContainerOGG:  {muxer: "ogg", audioCodecs: []AudioCodec{AudioCodecOpus, AudioCodecFLAC}},
+	ContainerOGA:  {muxer: "ogg", audioCodecs: []AudioCodec{AudioCodecOpus, AudioCodecFLAC}},
+	ContainerOpus: {muxer: "opus", audioCodecs: []AudioCodec{AudioCodecOpus}},
+	ContainerFLAC: {muxer: "flac", audioCodecs: []AudioCodec{AudioCodecFLAC}},
+	ContainerWAV:  {muxer: "wav", audioCodecs: []AudioCodec{AudioCodecPCM}},
+}
+
+// fragmentMovflags 分片方式对应的mov/mp4 muxer参数
+// frag_keyframe在每个关键帧处开始新分片，empty_moov在开头写入不含样本的moov，
+// default_base_moof让分片内的偏移相对于moof，便于单独传输每个分片
+var fragmentMovflags = map[FragmentMode]string{
+	FragmentModeFMP4: "+frag_keyframe+empty_moov+default_base_moof",
+	FragmentModeCMAF: "+frag_keyframe+empty_moov+default_base_moof+cmaf",
+}
+
+// videoEncoders 视频编码格式对应的ffmpeg编码器
+var videoEncoders = map[VideoCodec]string{
+	VideoCodecH264: "libx264",
+	VideoCodecHEVC: "libx265",
+	VideoCodecVP9:  "libvpx-vp9",
+	VideoCodecAV1:  "libaom-av1",
+}
+
+// maxCRF 各视频编码格式CRF的最大值
+var maxCRF = map[VideoCodec]int{
+	VideoCodecH264: 51,
+	VideoCodecHEVC: 51,
+	VideoCodecVP9:  63,
+	VideoCodecAV1:  63,
+}
+
+// losslessArgs 各视频编码格式的无损编码参数
+// x264的crf 0即为无损；x265的crf 0并非无损，需要通过x265-params开启；libvpx-vp9有单独的lossless选项
+var losslessArgs = map[VideoCodec][]string{
+	VideoCodecH264: {"-crf", "0"},
+	VideoCodecHEVC: {"-x265-params", "lossless=1"},
+	VideoCodecVP9:  {"-lossless", "1"},
+}
+
+// audioEncoders 音频编码格式对应的ffmpeg编码器
+var audioEncoders = map[AudioCodec]string{
+	AudioCodecAAC:  "aac",
+	AudioCodecOpus: "libopus",
+	AudioCodecMP3:  "libmp3lame",
+	AudioCodecFLAC: "flac",
+	AudioCodecPCM:  "pcm_s16le",
+}
+
+// presetCPUUsed x264风格的预设名称及其对应的libvpx/libaom -cpu-used值
+var presetCPUUsed = map[string]int{
+	"ultrafast": 8,
+	"superfast": 7,
+	"veryfast":  6,
+	"faster":    5,
+	"fast":      4,
+	"medium":    3,
+	"slow":      2,
+	"slower":    1,
+	"veryslow":  0,
+}
+
+// containerFromPath 根据文件扩展名推断容器格式
+func containerFromPath(path string) Container {
+	return Container(strings.ToLower(strings.TrimPrefix(filepath.Ext(path), ".")))
+}
+
+// containsCodec 判断编码格式是否在列表中
+func containsCodec[T comparable](codecs []T, codec T) bool {
+	for _, c := range codecs {
+		if c == codec {
+			return true
+		}
+	}
+	return false
+}
+
+// container 返回实际使用的容器格式
+func (p *TranscodeParams) container() Container {
+	if p.Container != "" {
+		return p.Container
+	}
+	return containerFromPath(p.OutputPath)
+}
+
+// videoCodec 返回实际使用的视频编码，不输出视频时返回空字符串
+func (p *TranscodeParams) videoCodec() VideoCodec {
+	spec := containerSpecs[p.container()]
+	if p.DisableVideo || len(spec.videoCodecs) == 0 {
+		return ""
+	}
+	if p.VideoCodec != "" {
+		return p.VideoCodec
+	}
+	return spec.videoCodecs[0]
+}
+
+// audioCodec 返回实际使用的音频编码，不输出音频时返回空字符串
+func (p *TranscodeParams) audioCodec() AudioCodec {
+	if p.DisableAudio {
+		return ""
+	}
+	if p.AudioCodec != "" {
+		return p.AudioCodec
+	}
+	return containerSpecs[p.container()].audioCodecs[0]
+}
+
+// Validate 校验转码参数
+// 返回值:
+//
+//	error: 参数无效时返回包装了ErrInvalidParams的错误
+func (p *TranscodeParams) Validate() error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidParams, fmt.Sprintf(format, args...))
+	}
+
+	if err := validateInput(p.InputPath, p.Input); err != nil {
+		return err
+	}
+	// 分片后不需要回到文件开头写入索引
+	seekable := containerSpecs[p.Container].seekable && p.Fragment == ""
+	if err := validateOutput(p.OutputPath, p.Output, p.Container, seekable); err != nil {
+		return err
+	}
+
+	container := p.container()
+	spec, ok := containerSpecs[container]
+	if !ok {
+		return invalid("unsupported container %q", container)
+	}
+	switch p.Fragment {
+	case "":
+	case FragmentModeFMP4:
+		if container != ContainerMP4 && container != ContainerMOV && container != ContainerM4A {
+			return invalid("fragmented output is not supported in container %q", container)
+		}
+	case FragmentModeCMAF:
+		if container != ContainerMP4 {
+			return invalid("cmaf output requires container %q, got %q", ContainerMP4, container)
+		}
+	default:
+		return invalid("unsupported fragment mode %q", p.Fragment)
+	}
+	if p.DisableVideo && p.DisableAudio {
+		return invalid("both video and audio are disabled")
+	}
+	if err := p.timeRange().validate(); err != nil {
+		return err
+	}
+
+	// 校验视频参数
+	if p.VideoCodec != "" && !p.DisableVideo {
+		if len(spec.videoCodecs) == 0 {
+			return invalid("container %q does not support video", container)
+		}
+		if p.VideoCodec != VideoCodecCopy {
+			if _, ok := videoEncoders[p.VideoCodec]; !ok {
+				return invalid("unsupported video codec %q", p.VideoCodec)
+			}
+			if !containsCodec(spec.videoCodecs, p.VideoCodec) {
+				return invalid("video codec %q is not supported in container %q", p.VideoCodec, container)
+			}
+		}
+	}
+	if p.CRF < 0 || p.VideoBitrate < 0 || p.Width < 0 || p.Height < 0 || p.FrameRate < 0 {
+		return invalid("crf, video bitrate, width, height and frame rate must not be negative")
+	}
+	if p.CRF > 0 && p.VideoBitrate > 0 {
+		return invalid("crf and video bitrate are mutually exclusive")
+	}
+	if p.Lossless && (p.CRF > 0 || p.VideoBitrate > 0) {
+		return invalid("lossless cannot be combined with crf or video bitrate")
+	}
+	videoCodec := p.videoCodec()
+	if videoCodec == VideoCodecCopy &&
+		(p.CRF > 0 || p.Lossless || p.VideoBitrate > 0 || p.Preset != "" || p.Width > 0 || p.Height > 0 || p.FrameRate > 0 || p.PixelFormat != "") {
+		return invalid("video encoding options cannot be used with stream copy")
+	}
+	if _, ok := losslessArgs[videoCodec]; p.Lossless && !ok {
+		return invalid("lossless encoding is not supported for video codec %q", videoCodec)
+	}
+	if max, ok := maxCRF[videoCodec]; ok && p.CRF > max {
+		return invalid("crf %d is out of range 0-%d for %s", p.CRF, max, videoCodec)
+	}
+	if _, ok := presetCPUUsed[p.Preset]; p.Preset != "" && !ok {
+		return invalid("unsupported preset %q", p.Preset)
+	}
+
+	// 校验音频参数
+	if p.AudioCodec != "" && !p.DisableAudio && p.AudioCodec != AudioCodecCopy {
+		if _, ok := audioEncoders[p.AudioCodec]; !ok {
+			return invalid("unsupported audio codec %q", p.AudioCodec)
+		}
+		if !containsCodec(spec.audioCodecs, p.AudioCodec) {
+			return invalid("audio codec %q is not supported in container %q", p.AudioCodec, container)
+		}
+	}
+	if p.AudioBitrate < 0 || p.AudioSampleRate < 0 || p.AudioChannels < 0 {
+		return invalid("audio bitrate, sample rate and channels must not be negative")
+	}
+	if p.audioCodec() == AudioCodecCopy && (p.AudioBitrate > 0 || p.AudioSampleRate > 0 || p.AudioChannels > 0) {
+		return invalid("audio encoding options cannot be used with stream copy")
+	}
+
+	return nil
+}
+
+// buildTranscodeArgs 校验参数并构建转码的ffmpeg命令行参数
+func buildTranscodeArgs(p *TranscodeParams) ([]string, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	spec := containerSpecs[p.container()]
+	args := append([]string{"-y"}, p.timeRange().wrapInput(inputURL(p.InputPath, p.Input))...)
+
+	// 视频参数
+	switch videoCodec := p.videoCodec(); videoCodec {
+	case "":
+		args = append(args, "-vn")
+	case VideoCodecCopy:
+		args = append(args, "-c:v", "copy")
+	default:
+		args = append(args, "-c:v", videoEncoders[videoCodec])
+		if p.CRF > 0 {
+			args = append(args, "-crf", strconv.Itoa(p.CRF))
+			// libvpx-vp9需要把码率设为0才会进入恒定质量模式
+			if videoCodec == VideoCodecVP9 {
+				args = append(args, "-b:v", "0")
+			}
+		}
+		if p.Lossless {
+			args = append(args, losslessArgs[videoCodec]...)
+		}
+		if p.VideoBitrate > 0 {
+			args = append(args, "-b:v", fmt.Sprintf("%dk", p.VideoBitrate))
+		}
+		if p.Preset != "" {
+			switch videoCodec {
+			case VideoCodecH264, VideoCodecHEVC:
+				args = append(args, "-preset", p.Preset)
+			case VideoCodecVP9:
+				// libvpx-vp9在good质量模式下cpu-used的范围为0-5
+				args = append(args, "-cpu-used", strconv.Itoa(min(presetCPUUsed[p.Preset], 5)))
+			default:
+				args = append(args, "-cpu-used", strconv.Itoa(presetCPUUsed[p.Preset]))
+			}
+		}
+		if p.Width > 0 || p.Height > 0 {
+			// 只指定一边时另一边按比例缩放并保持为偶数
+			width, height := p.Width, p.Height
+			if width == 0 {
+				width = -2
+			}
+			if height == 0 {
+				height = -2
+			}
+			args = append(args, "-vf", fmt.Sprintf("scale=%d:%d", width, height))
+		}
+		if p.FrameRate > 0 {
+			args = append(args, "-r", strconv.FormatFloat(p.FrameRate, 'f', -1, 64))
+		}
+		if p.PixelFormat != "" {
+			args = append(args, "-pix_fmt", p.PixelFormat)
+		}
+		// Apple设备要求HEVC使用hvc1标签
+		if videoCodec == VideoCodecHEVC && (p.container() == ContainerMP4 || p.container() == ContainerMOV) {
+			args = append(args, "-tag:v", "hvc1")
+		}
+	}
+
+	// 音频参数
+	switch audioCodec := p.audioCodec(); audioCodec {
+	case "":
+		args = append(args, "-an")
+	case AudioCodecCopy:
+		args = append(args, "-c:a", "copy")
+	default:
+		args = appendAudioEncodeArgs(args, audioCodec, p.AudioBitrate, p.AudioSampleRate, p.AudioChannels)
+	}
+
+	switch {
+	case p.Fragment != "":
+		args = append(args, "-movflags", fragmentMovflags[p.Fragment])
+	case spec.faststart:
+		args = append(args, "-movflags", "+faststart")
+	}
+	args = append(args, "-f", spec.muxer, outputURL(p.OutputPath, p.Output))
+	return args, nil
+}
+
+// appendAudioEncodeArgs 追加音频编码器、码率、采样率和声道数参数，值为0的参数保持编码器默认值
+func appendAudioEncodeArgs(args []string, codec AudioCodec, bitrate, sampleRate, channels int) []string {
+	args = append(args, "-c:a", audioEncoders[codec])
+	if bitrate > 0 {
+		args = append(args, "-b:a", fmt.Sprintf("%dk", bitrate))
+	}
+	if sampleRate > 0 {
+		args = append(args, "-ar", strconv.Itoa(sampleRate))
+	}
+	if channels > 0 {
+		args = append(args, "-ac", strconv.Itoa(channels))
+	}
+	return args
+}
+
+// Transcode 按指定的容器和编码参数转码媒体文件
+// 所有参数在启动ffmpeg之前校验，ctx被取消或超时时会终止ffmpeg进程组并删除不完整的输出文件
+// 设置了Input或Output时通过stdin读取输入、通过stdout写入输出，需要定位的容器不能写入Output
+// 参数:
+//
+//	ctx: 控制命令生命周期的上下文
+//	params: 转码参数配置
+//
+// 返回值:
+//
+//	error: 参数无效时返回包装了ErrInvalidParams的错误；转码失败时返回*CommandError
+//
+// 示例:
+//
+//	err := ffmpeg.Transcode(ctx, &ffmpeg.TranscodeParams{
+//	    InputPath:    "input.mov",
+//	    OutputPath:   "output.mp4",
+//	    VideoCodec:   ffmpeg.VideoCodecH264,
+//	    CRF:          23,
+//	    Preset:       "medium",
+//	    Height:       720,
+//	    AudioCodec:   ffmpeg.AudioCodecAAC,
+//	    AudioBitrate: 128,
+//	})
+func (f *FFmpeg) Transcode(ctx context.Context, params *TranscodeParams) error {
+	args, err := buildTranscodeArgs(params)
+	if err != nil {
+		return err
+	}
+
+	cleanup := removeNewOutputFile(params.OutputPath)
+	err = f.run(ctx, &runOptions{
+		binary:   f.FFmpegPath,
+		args:     args,
+		stdin:    params.Input,
+		stdout:   params.Output,
+		progress: true,
+		total:    f.rangeDuration(ctx, params.InputPath, params.timeRange()),
+	})
+	if err != nil {
+		// 取消或超时后删除本次新建的不完整输出文件
+		if ctx.Err() != nil && params.Output == nil {
+			cleanup()
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,274 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBuildTranscodeArgs 测试转码参数到ffmpeg命令行参数的转换
+func TestBuildTranscodeArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		params TranscodeParams
+		want   []string
+	}{
+		{
+			name:   "defaults from extension",
+			params: TranscodeParams{InputPath: "in.mov", OutputPath: "out.mp4"},
+			want:   []string{"-y", "-i", "in.mov", "-c:v", "libx264", "-c:a", "aac", "-movflags", "+faststart", "-f", "mp4", "out.mp4"},
+		},
+		{
+			name: "h264 crf preset and scaling",
+			params: TranscodeParams{
+				InputPath: "in.mov", OutputPath: "out.mp4",
+				VideoCodec: VideoCodecH264, CRF: 23, Preset: "slow", Height: 720, FrameRate: 29.97, PixelFormat: "yuv420p",
+				AudioCodec: AudioCodecAAC, AudioBitrate: 128, AudioSampleRate: 44100, AudioChannels: 2,
+			},
+			want: []string{"-y", "-i", "in.mov",
+				"-c:v", "libx264", "-crf", "23", "-preset", "slow", "-vf", "scale=-2:720", "-r", "29.97", "-pix_fmt", "yuv420p",
+				"-c:a", "aac", "-b:a", "128k", "-ar", "44100", "-ac", "2",
+				"-movflags", "+faststart", "-f", "mp4", "out.mp4"},
+		},
+		{
+			name:   "hevc in mp4 uses hvc1 tag",
+			params: TranscodeParams{InputPath: "in.mkv", OutputPath: "out.mp4", VideoCodec: VideoCodecHEVC, VideoBitrate: 2500},
+			want: []string{"-y", "-i", "in.mkv", "-c:v", "libx265", "-b:v", "2500k", "-tag:v", "hvc1",
+				"-c:a", "aac", "-movflags", "+faststart", "-f", "mp4", "out.mp4"},
+		},
+		{
+			name:   "vp9 constant quality in webm",
+			params: TranscodeParams{InputPath: "in.mp4", OutputPath: "out.webm", CRF: 31, Preset: "ultrafast"},
+			want: []string{"-y", "-i", "in.mp4", "-c:v", "libvpx-vp9", "-crf", "31", "-b:v", "0", "-cpu-used", "5",
+				"-c:a", "libopus", "-f", "webm", "out.webm"},
+		},
+		{
+			name:   "lossless h264",
+			params: TranscodeParams{InputPath: "in.mov", OutputPath: "out.mkv", VideoCodec: VideoCodecH264, Lossless: true, Preset: "veryslow"},
+			want: []string{"-y", "-i", "in.mov", "-c:v", "libx264", "-crf", "0", "-preset", "veryslow",
+				"-c:a", "libopus", "-f", "matroska", "out.mkv"},
+		},
+		{
+			name:   "lossless hevc",
+			params: TranscodeParams{InputPath: "in.mov", OutputPath: "out.mkv", VideoCodec: VideoCodecHEVC, Lossless: true},
+			want: []string{"-y", "-i", "in.mov", "-c:v", "libx265", "-x265-params", "lossless=1",
+				"-c:a", "libopus", "-f", "matroska", "out.mkv"},
+		},
+		{
+			name:   "lossless vp9",
+			params: TranscodeParams{InputPath: "in.mp4", OutputPath: "out.webm", Lossless: true},
+			want: []string{"-y", "-i", "in.mp4", "-c:v", "libvpx-vp9", "-lossless", "1",
+				"-c:a", "libopus", "-f", "webm", "out.webm"},
+		},
+		{
+			name:   "stream copy",
+			params: TranscodeParams{InputPath: "in.mp4", OutputPath: "out.mkv", VideoCodec: VideoCodecCopy, AudioCodec: AudioCodecCopy},
+			want:   []string{"-y", "-i", "in.mp4", "-c:v", "copy", "-c:a", "copy", "-f", "matroska", "out.mkv"},
+		},
+		{
+			name:   "audio only container drops video",
+			params: TranscodeParams{InputPath: "in.mp4", OutputPath: "out.flac", AudioSampleRate: 48000},
+			want:   []string{"-y", "-i", "in.mp4", "-vn", "-c:a", "flac", "-ar", "48000", "-f", "flac", "out.flac"},
+		},
+		{
+			name:   "explicit container and disabled audio",
+			params: TranscodeParams{InputPath: "in.mp4", OutputPath: "out.bin", Container: ContainerMPEGTS, DisableAudio: true},
+			want:   []string{"-y", "-i", "in.mp4", "-c:v", "libx264", "-an", "-f", "mpegts", "out.bin"},
+		},
+		{
+			name:   "fragmented remux to writer",
+			params: TranscodeParams{InputPath: "in.mkv", Output: &bytes.Buffer{}, Container: ContainerMP4, Fragment: FragmentModeFMP4, VideoCodec: VideoCodecCopy, AudioCodec: AudioCodecCopy},
+			want: []string{"-y", "-i", "in.mkv", "-c:v", "copy", "-c:a", "copy",
+				"-movflags", "+frag_keyframe+empty_moov+default_base_moof", "-f", "mp4", "pipe:1"},
+		},
+		{
+			name:   "cmaf replaces faststart",
+			params: TranscodeParams{InputPath: "in.mov", OutputPath: "out.mp4", Fragment: FragmentModeCMAF},
+			want: []string{"-y", "-i", "in.mov", "-c:v", "libx264", "-c:a", "aac",
+				"-movflags", "+frag_keyframe+empty_moov+default_base_moof+cmaf", "-f", "mp4", "out.mp4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildTranscodeArgs(&tt.params)
+			if err != nil {
+				t.Fatalf("buildTranscodeArgs() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildTranscodeArgs() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTranscodeParamsValidate 测试无效参数在启动ffmpeg之前被拒绝
+func TestTranscodeParamsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		params TranscodeParams
+	}{
+		{"missing input", TranscodeParams{OutputPath: "out.mp4"}},
+		{"missing output", TranscodeParams{InputPath: "in.mp4"}},
+		{"unknown container", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.xyz"}},
+		{"unknown video codec", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.mkv", VideoCodec: "mpeg2"}},
+		{"codec not allowed in container", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.webm", VideoCodec: VideoCodecH264}},
+		{"audio codec not allowed in container", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.webm", AudioCodec: AudioCodecAAC}},
+		{"video in audio container", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.mp3", VideoCodec: VideoCodecH264}},
+		{"crf out of range", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.mp4", CRF: 52}},
+		{"crf and bitrate", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.mp4", CRF: 23, VideoBitrate: 1000}},
+		{"lossless and crf", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.mp4", CRF: 18, Lossless: true}},
+		{"lossless av1", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.mp4", VideoCodec: VideoCodecAV1, Lossless: true}},
+		{"lossless stream copy", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.mp4", VideoCodec: VideoCodecCopy, Lossless: true}},
+		{"unknown preset", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.mp4", Preset: "warp"}},
+		{"negative width", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.mp4", Width: -1}},
+		{"copy with scaling", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.mp4", VideoCodec: VideoCodecCopy, Width: 640}},
+		{"audio copy with bitrate", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.mp4", AudioCodec: AudioCodecCopy, AudioBitrate: 128}},
+		{"everything disabled", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.mp4", DisableVideo: true, DisableAudio: true}},
+		{"unknown fragment mode", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.mp4", Fragment: "dash"}},
+		{"fragment in matroska", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.mkv", Fragment: FragmentModeFMP4}},
+		{"cmaf in mov", TranscodeParams{InputPath: "in.mp4", OutputPath: "out.mov", Fragment: FragmentModeCMAF}},
+		{"unfragmented mp4 to writer", TranscodeParams{InputPath: "in.mp4", Output: &bytes.Buffer{}, Container: ContainerMP4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(); !errors.Is(err, ErrInvalidParams) {
+				t.Errorf("Validate() error = %v, want ErrInvalidParams", err)
+			}
+		})
+	}
+}
+
+// TestTranscodeInvalidParamsDoesNotSpawn 测试参数无效时不会启动ffmpeg
+func TestTranscodeInvalidParamsDoesNotSpawn(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "spawned")
+	fakeFFmpeg := writeFakeBinary(t, "ffmpeg", "touch "+marker+"\n")
+
+	ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create FFmpeg instance: %v", err)
+	}
+
+	err = ffmpeg.Transcode(context.Background(), &TranscodeParams{
+		InputPath:  "in.mp4",
+		OutputPath: "out.webm",
+		VideoCodec: VideoCodecH264,
+	})
+	if !errors.Is(err, ErrInvalidParams) {
+		t.Fatalf("Expected ErrInvalidParams, got %v", err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("ffmpeg was spawned for invalid parameters")
+	}
+}
+
+// TestTranscodeFragmentedStream 测试分片MP4的-movflags位于输出之前，且通过pipe:1写出的数据到达io.Writer
+func TestTranscodeFragmentedStream(t *testing.T) {
+	for mode, movflags := range fragmentMovflags {
+		t.Run(string(mode), func(t *testing.T) {
+			argsFile := filepath.Join(t.TempDir(), "args")
+			// 每行记录一个参数，并向stdout写出模拟的分片
+			fakeFFmpeg := writeFakeBinary(t, "ffmpeg", `printf '%s\n' "$@" > "`+argsFile+`"
+printf 'ftyp moov moof mdat'
+`)
+			ffmpeg, err := NewFFmpegWithPath(fakeFFmpeg, "", nil)
+			if err != nil {
+				t.Fatalf("Failed to create FFmpeg instance: %v", err)
+			}
+
+			var output bytes.Buffer
+			err = ffmpeg.Transcode(context.Background(), &TranscodeParams{
+				InputPath:  "input.mkv",
+				Output:     &output,
+				Container:  ContainerMP4,
+				Fragment:   mode,
+				VideoCodec: VideoCodecCopy,
+				AudioCodec: AudioCodecCopy,
+			})
+			if err != nil {
+				t.Fatalf("Transcode failed: %v", err)
+			}
+			if output.String() != "ftyp moov moof mdat" {
+				t.Errorf("Expected stdout to reach the writer, got %q", output.String())
+			}
+
+			data, err := os.ReadFile(argsFile)
+			if err != nil {
+				t.Fatalf("Failed to read recorded arguments: %v", err)
+			}
+			args := strings.Split(strings.TrimSpace(string(data)), "\n")
+			want := []string{"-movflags", movflags, "-f", "mp4", "pipe:1"}
+			if len(args) < len(want) || !reflect.DeepEqual(args[len(args)-len(want):], want) {
+				t.Errorf("Expected args to end with %q, got %q", want, args)
+			}
+			if strings.Contains(string(data), "faststart") {
+				t.Errorf("Fragmented output must not use faststart, got %q", args)
+			}
+		})
+	}
+}
+
+// TestTranscodeFragmentedReprobe 测试分片MP4写入io.Writer后仍能被ffprobe正确识别
+// 需要真实的ffmpeg，设置环境变量FFMPEG_INTEGRATION=1时运行
+func TestTranscodeFragmentedReprobe(t *testing.T) {
+	if os.Getenv("FFMPEG_INTEGRATION") == "" {
+		t.Skip("Skipping - set FFMPEG_INTEGRATION=1 to run against a real ffmpeg")
+	}
+	ffmpeg, err := NewFFmpeg(nil)
+	if err != nil {
+		t.Fatalf("No ffmpeg available: %v", err)
+	}
+
+	// 生成2秒带音频的测试视频
+	input := filepath.Join(t.TempDir(), "input.mkv")
+	err = ffmpeg.run(context.Background(), &runOptions{
+		binary: ffmpeg.FFmpegPath,
+		args: []string{"-y",
+			"-f", "lavfi", "-i", "testsrc=duration=2:size=320x240:rate=25",
+			"-f", "lavfi", "-i", "sine=duration=2",
+			"-c:v", "mpeg4", "-c:a", "aac", "-shortest", input},
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate test input: %v", err)
+	}
+
+	for _, mode := range []FragmentMode{FragmentModeFMP4, FragmentModeCMAF} {
+		t.Run(string(mode), func(t *testing.T) {
+			// 直接复制流的remux，输出到内存
+			var output bytes.Buffer
+			err := ffmpeg.Transcode(context.Background(), &TranscodeParams{
+				InputPath:  input,
+				Output:     &output,
+				Container:  ContainerMP4,
+				Fragment:   mode,
+				VideoCodec: VideoCodecCopy,
+				AudioCodec: AudioCodecCopy,
+			})
+			if err != nil {
+				t.Fatalf("Transcode failed: %v", err)
+			}
+
+			outputPath := filepath.Join(t.TempDir(), "output.mp4")
+			if err := os.WriteFile(outputPath, output.Bytes(), 0644); err != nil {
+				t.Fatalf("Failed to write output: %v", err)
+			}
+			info, err := ffmpeg.Probe(context.Background(), outputPath)
+			if err != nil {
+				t.Fatalf("Failed to probe fragmented output: %v", err)
+			}
+			if len(info.VideoStreams()) != 1 || len(info.AudioStreams()) != 1 {
+				t.Fatalf("Expected 1 video and 1 audio stream, got %+v", info.Streams)
+			}
+			if info.Format.Duration < 1900*time.Millisecond || info.Format.Duration > 2100*time.Millisecond {
+				t.Errorf("Unexpected duration of fragmented output: %v", info.Format.Duration)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// timeRange 描述只处理输入文件一部分时的时间范围
+// end和duration只能设置一个，都为0表示处理到输入文件结尾
+type timeRange struct {
+	start    time.Duration // 开始时间
+	end      time.Duration // 结束时间
+	duration time.Duration // 持续时长
+	accurate bool          // 使用输出端精确定位
+}
+
+// validate 校验时间范围
+func (r timeRange) validate() error {
+	if r.start < 0 || r.end < 0 || r.duration < 0 {
+		return fmt.Errorf("%w: start, end and duration must not be negative", ErrInvalidParams)
+	}
+	if r.end > 0 && r.duration > 0 {
+		return fmt.Errorf("%w: end and duration are mutually exclusive", ErrInvalidParams)
+	}
+	if r.end > 0 && r.end <= r.start {
+		return fmt.Errorf("%w: end %v must be after start %v", ErrInvalidParams, r.end, r.start)
+	}
+	return nil
+}
+
+// length 返回时间范围的长度，0表示处理到输入文件结尾
+func (r timeRange) length() time.Duration {
+	if r.duration > 0 {
+		return r.duration
+	}
+	if r.end > 0 {
+		return r.end - r.start
+	}
+	return 0
+}
+
+// inputArgs 返回放在-i之前的参数
+// 非精确模式下在输入端定位，ffmpeg直接跳到start之前最近的关键帧，速度快；
+// 重新编码时结果仍是精确的，直接复制流时则从关键帧开始
+func (r timeRange) inputArgs() []string {
+	if r.start > 0 && !r.accurate {
+		return []string{"-ss", formatSeconds(r.start)}
+	}
+	return nil
+}
+
+// outputArgs 返回放在-i之后的参数
+// 精确模式下在输出端定位，ffmpeg解码并丢弃start之前的所有帧，保证逐帧精确
+func (r timeRange) outputArgs() []string {
+	var args []string
+	if r.start > 0 && r.accurate {
+		args = append(args, "-ss", formatSeconds(r.start))
+	}
+	if length := r.length(); length > 0 {
+		args = append(args, "-t", formatSeconds(length))
+	}
+	return args
+}
+
+// wrapInput 构建带时间范围的输入参数，即inputArgs、-i和outputArgs
+func (r timeRange) wrapInput(inputPath string) []string {
+	args := append(r.inputArgs(), "-i", inputPath)
+	return append(args, r.outputArgs()...)
+}
+
+// formatSeconds 将时长格式化为ffmpeg接受的秒数
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// rangeDuration 获取时间范围内实际要处理的时长，用于计算进度百分比
+// 无法获取输入文件时长时返回时间范围本身的长度
+func (f *FFmpeg) rangeDuration(ctx context.Context, inputPath string, r timeRange) time.Duration {
+	total := f.inputDuration(ctx, inputPath)
+	if total <= 0 {
+		return r.length()
+	}
+	return r.within(total)
+}
+
+// within 返回时间范围落在总时长为total的输入文件内的实际长度
+func (r timeRange) within(total time.Duration) time.Duration {
+	end := total
+	if length := r.length(); length > 0 && r.start+length < total {
+		end = r.start + length
+	}
+	if end <= r.start {
+		return 0
+	}
+	return end - r.start
+}
+
+// timeRange 转换为内部使用的时间范围
+func (r TimeRange) timeRange() timeRange {
+	return timeRange{start: r.Start, end: r.End, duration: r.Duration, accurate: r.AccurateSeek}
+}
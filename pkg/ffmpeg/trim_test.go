@@ -0,0 +1,79 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestTimeRangeArgs 测试快速定位和精确定位生成的参数
+func TestTimeRangeArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		r    timeRange
+		want []string
+	}{
+		{"whole input", timeRange{}, []string{"-i", "in.mp4"}},
+		{"fast seek with end", timeRange{start: 90 * time.Second, end: 100500 * time.Millisecond}, []string{"-ss", "90", "-i", "in.mp4", "-t", "10.5"}},
+		{"accurate seek with duration", timeRange{start: 1500 * time.Millisecond, duration: 2 * time.Second, accurate: true}, []string{"-i", "in.mp4", "-ss", "1.5", "-t", "2"}},
+		{"duration only", timeRange{duration: time.Minute}, []string{"-i", "in.mp4", "-t", "60"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.r.wrapInput("in.mp4"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrapInput() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTimeRangeValidate 测试无效的时间范围被拒绝
+func TestTimeRangeValidate(t *testing.T) {
+	invalid := []timeRange{
+		{start: -time.Second},
+		{end: 5 * time.Second, duration: 5 * time.Second},
+		{start: 10 * time.Second, end: 5 * time.Second},
+	}
+	for _, r := range invalid {
+		if err := r.validate(); !errors.Is(err, ErrInvalidParams) {
+			t.Errorf("validate(%+v) error = %v, want ErrInvalidParams", r, err)
+		}
+	}
+
+	// 无效的时间范围在启动ffmpeg之前返回
+	_, err := (&FFmpeg{}).SplitVideo(&SplitVideoParams{InputPath: "in.mp4", OutputDir: t.TempDir(), SegmentTime: 10, TimeRange: TimeRange{Start: time.Minute, End: time.Second}})
+	if !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("Expected ErrInvalidParams from SplitVideo, got %v", err)
+	}
+}
+
+// TestRangeDuration 测试进度总时长按裁剪后的范围计算
+func TestRangeDuration(t *testing.T) {
+	ffmpeg := &FFmpeg{
+		FFprobePath: writeFakeFFprobe(t),
+		Callback:    func(*Progress) {},
+	}
+
+	// 输入文件时长为120.12秒
+	tests := []struct {
+		name string
+		r    timeRange
+		want time.Duration
+	}{
+		{"whole input", timeRange{}, 120120 * time.Millisecond},
+		{"start only", timeRange{start: 100 * time.Second}, 20120 * time.Millisecond},
+		{"inside input", timeRange{start: 10 * time.Second, end: 40 * time.Second}, 30 * time.Second},
+		{"past end of input", timeRange{start: 110 * time.Second, duration: time.Minute}, 10120 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ffmpeg.rangeDuration(context.Background(), "input.mp4", tt.r); got != tt.want {
+				t.Errorf("rangeDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,5 +1,12 @@
 package ffmpeg
 
+import (
+	"image"
+	"io"
+	"log/slog"
+	"time"
+)
+
 // ProgressCallback 定义进度回调函数类型
 // 用于在FFmpeg处理过程中实时获取进度信息
 // 参数:
@@ -18,18 +25,57 @@ package ffmpeg
 type ProgressCallback func(progress *Progress)
 
 // Progress 定义进度信息结构体
-// 包含FFmpeg处理过程中的进度数据
+// 包含FFmpeg处理过程中的进度数据，由ffmpeg的-progress输出解析而来
 // 字段:
 //
-//	Percentage: 进度百分比，范围0-100
+//	Percentage: 进度百分比，范围0-100，总时长未知时为0
 //	Current: 当前处理时间，单位为毫秒
-//	Total: 总时长，单位为毫秒
+//	Total: 总时长，单位为毫秒，通过ffprobe获取，未知时为0
 //	Status: 当前状态，如"processing"、"completed"等
+//	Frame: 已输出的帧数
+//	FPS: 当前处理帧率
+//	Bitrate: 当前输出码率，单位为kbit/s
+//	Speed: 处理速度，相对于实时播放的倍数
+//	OutSize: 已输出的数据大小，单位为字节
+//	ETA: 预计剩余时间，无法估算时为0
 type Progress struct {
-	Percentage float64 // 进度百分比 (0-100)
-	Current    int64   // 当前处理时间 (毫秒)
-	Total      int64   // 总时长 (毫秒)
-	Status     string  // 当前状态
+	Percentage float64       // 进度百分比 (0-100)
+	Current    int64         // 当前处理时间 (毫秒)
+	Total      int64         // 总时长 (毫秒)
+	Status     string        // 当前状态
+	Frame      int64         // 已输出帧数
+	FPS        float64       // 处理帧率
+	Bitrate    float64       // 输出码率 (kbit/s)
+	Speed      float64       // 处理速度 (倍速)
+	OutSize    int64         // 已输出大小 (字节)
+	ETA        time.Duration // 预计剩余时间
+}
+
+// BinarySource 定义ffmpeg/ffprobe二进制文件的来源
+type BinarySource string
+
+const (
+	BinarySourceExplicit BinarySource = "explicit" // 调用方显式指定
+	BinarySourceEnv      BinarySource = "env"      // FFMPEG_PATH/FFPROBE_PATH环境变量
+	BinarySourcePath     BinarySource = "path"     // $PATH中查找到
+	BinarySourceEmbedded BinarySource = "embedded" // 释放内置的二进制文件
+)
+
+// Options 创建FFmpeg工具的选项结构体
+// 用于NewFFmpegWithOptions
+// 字段:
+//
+//	FFmpegPath: 显式指定的FFmpeg二进制文件路径，为空则自动查找
+//	FFprobePath: 显式指定的FFprobe二进制文件路径，为空则自动查找
+//	ExtractPath: 内置二进制文件释放路径，为空则使用临时目录
+//	Callback: 进度回调函数
+//	Logger: 日志记录器，为nil时不输出日志
+type Options struct {
+	FFmpegPath  string           // 显式指定的FFmpeg路径
+	FFprobePath string           // 显式指定的FFprobe路径
+	ExtractPath string           // 内置二进制文件释放路径
+	Callback    ProgressCallback // 进度回调函数
+	Logger      *slog.Logger     // 日志记录器
 }
 
 // FFmpeg 定义FFmpeg工具结构体
@@ -40,15 +86,38 @@ type Progress struct {
 //	FFprobePath: FFprobe二进制文件路径
 //	ExtractPath: 二进制文件释放路径
 //	Callback: 进度回调函数
+//	Logger: 日志记录器，为nil时不输出日志
+//	FFmpegSource: FFmpeg二进制文件的来源
+//	FFprobeSource: FFprobe二进制文件的来源
 //
 // 注意:
 //
 //	该结构体不应直接实例化，而应通过NewFFmpeg系列函数创建
+//	每个实例只使用自身的二进制路径启动子进程，不会修改进程环境变量，
+//	因此不同路径的实例可以在多个goroutine中并发使用；
+//	并发调用期间不应再通过SetFFmpegPath等方法修改实例字段
 type FFmpeg struct {
-	FFmpegPath  string           // FFmpeg二进制文件路径
-	FFprobePath string           // FFprobe二进制文件路径
-	ExtractPath string           // 二进制文件释放路径
-	Callback    ProgressCallback // 进度回调函数
+	FFmpegPath    string           // FFmpeg二进制文件路径
+	FFprobePath   string           // FFprobe二进制文件路径
+	ExtractPath   string           // 二进制文件释放路径
+	Callback      ProgressCallback // 进度回调函数
+	Logger        *slog.Logger     // 日志记录器
+	FFmpegSource  BinarySource     // FFmpeg二进制文件来源
+	FFprobeSource BinarySource     // FFprobe二进制文件来源
+}
+
+// TimeRange 只处理输入文件一部分时的时间范围，嵌入在各操作的参数结构体中
+// 字段:
+//
+//	Start: 开始时间，0表示从头开始
+//	End: 结束时间，不能与Duration同时设置，0表示处理到结尾
+//	Duration: 处理时长，不能与End同时设置
+//	AccurateSeek: 在输出端精确定位，逐帧精确但需要解码Start之前的所有内容；默认在输入端快速定位；提取关键帧、流式提取帧、精灵图和预览动画不支持
+type TimeRange struct {
+	Start        time.Duration // 开始时间
+	End          time.Duration // 结束时间
+	Duration     time.Duration // 处理时长
+	AccurateSeek bool          // 精确定位
 }
 
 // ExtractAudioParams 提取音频流参数结构体
@@ -57,24 +126,109 @@ type FFmpeg struct {
 //
 //	InputPath: 输入视频文件路径
 //	OutputPath: 输出音频文件路径
+//	Input: 输入数据流，通过stdin传给ffmpeg，不能与InputPath同时设置；不支持AudioTrack、Language、CopyIfCompatible和直接复制
+//	Output: 输出数据流，ffmpeg通过stdout写入，不能与OutputPath同时设置；必须指定Container，且容器不能需要定位（如m4a）
+//	Container: 输出容器格式，为空则根据输出文件扩展名推断
+//	Codec: 音频编码格式，为空则使用容器的默认编码，AudioCodecCopy表示直接复制（源编码与容器不兼容时返回错误）
+//	Bitrate: 音频码率，单位为kbit/s，不能与Quality同时设置
+//	SampleRate: 音频采样率，单位为Hz，0表示保持原始采样率
+//	Channels: 音频声道数，0表示保持原始声道数
+//	Quality: 可变码率质量等级，1（最低）到10（最高），仅支持mp3和aac，0表示不使用可变码率
+//	CopyIfCompatible: 源编码与容器兼容（指定了Codec时还需与Codec一致）且未设置编码参数时直接复制，否则重新编码
+//	AudioTrack: 选择第几条音轨，从1开始且只计算音频流，设置了Language时在该语言的音轨中选择，0表示默认音轨；
+//	  不是Probe返回的StreamInfo.Index，MediaInfo.AudioStreams()中的第i个（从0开始）对应AudioTrack为i+1
+//	Language: 按语言代码选择音轨，如"eng"、"chi"
+//	TimeRange: 时间范围，只处理输入文件的一部分
 type ExtractAudioParams struct {
-	InputPath  string // 输入视频文件路径
-	OutputPath string // 输出音频文件路径
+	InputPath        string     // 输入视频文件路径
+	OutputPath       string     // 输出音频文件路径
+	Input            io.Reader  // 输入数据流
+	Output           io.Writer  // 输出数据流
+	Container        Container  // 输出容器格式
+	Codec            AudioCodec // 音频编码格式
+	Bitrate          int        // 音频码率 (kbit/s)
+	SampleRate       int        // 音频采样率 (Hz)
+	Channels         int        // 音频声道数
+	Quality          int        // 可变码率质量等级 (1-10)
+	CopyIfCompatible bool       // 兼容时直接复制音频流
+	AudioTrack       int        // 音轨序号，从1开始
+	Language         string     // 音轨语言代码
+	TimeRange                   // 时间范围
 }
 
 // SplitVideoParams 视频分段参数结构体
 // 用于配置将视频文件分割为多个小段的参数
+// SegmentTime、CutPoints、ByChapter和MaxSize是互斥的切分依据，必须且只能设置一个
 // 字段:
 //
 //	InputPath: 输入视频文件路径
+//	Input: 输入数据流，通过stdin传给ffmpeg，不能与InputPath同时设置；只支持按SegmentTime或CutPoints切分，不支持SplitModeScene和直接复制到音频格式
 //	OutputDir: 输出目录，用于存放分段后的视频文件
 //	SegmentTime: 分段时长，单位为秒
+//	CutPoints: 按指定的时间点切分，时间相对于输入文件开头
+//	ByChapter: 按输入文件的章节切分，每个章节输出一个文件
+//	MaxSize: 每个分段的最大文件大小，单位为字节，先根据输入文件的码率估算分段时长，有分段超出时缩短时长重新切分
 //	OutputPrefix: 输出文件名前缀
+//	Format: 分段的容器格式，决定文件扩展名和muxer，为空时沿用输入文件的格式；音频格式（如m4a、mp3、opus）只输出一条音轨，直接复制时使用默认音轨且源音频编码必须与该格式兼容
+//	Mode: 分段方式，为空时使用SplitModeCopy；SplitModeCopy只能在关键帧处切分，需要精确切分时使用SplitModeReencode
+//	SceneThreshold: SplitModeScene的场景切换阈值，范围0-1，值越小越敏感，0表示使用默认值0.4
+//	TimeRange: 时间范围，只处理输入文件的一部分
 type SplitVideoParams struct {
-	InputPath    string // 输入视频文件路径
-	OutputDir    string // 输出目录
-	SegmentTime  int    // 分段时长 (秒)
-	OutputPrefix string // 输出文件名前缀
+	InputPath      string          // 输入视频文件路径
+	Input          io.Reader       // 输入数据流
+	OutputDir      string          // 输出目录
+	SegmentTime    int             // 分段时长 (秒)
+	CutPoints      []time.Duration // 切分时间点
+	ByChapter      bool            // 按章节切分
+	MaxSize        int64           // 分段最大大小 (字节)
+	OutputPrefix   string          // 输出文件名前缀
+	Format         Container       // 分段容器格式
+	Mode           SplitMode       // 分段方式
+	SceneThreshold float64         // 场景切换阈值
+	TimeRange                      // 时间范围
+}
+
+// SplitMode 定义视频分段方式
+type SplitMode string
+
+const (
+	// SplitModeCopy 直接复制流，在每个SegmentTime之后的第一个关键帧处切分，速度最快但分段时长不精确
+	SplitModeCopy SplitMode = "copy"
+	// SplitModeReencode 重新编码并在每个SegmentTime处强制插入关键帧，每个分段时长精确
+	SplitModeReencode SplitMode = "reencode"
+	// SplitModeScene 重新编码，在距上一个切分点至少SegmentTime之后的第一个场景切换处切分，只能与SegmentTime一起使用
+	SplitModeScene SplitMode = "scene"
+)
+
+// Segment 分段结果结构体
+// 每个分段紧接上一个分段的结束时间开始；SplitModeCopy指定了开始时间时，第一个分段从开始时间之前最近的关键帧开始，Start和End按该关键帧的时间计算；
+// 输入为io.Reader时无法定位该关键帧，Start和End按开始时间计算，与实际时间相差不超过一个关键帧间隔
+// 字段:
+//
+//	Path: 分段文件路径
+//	Index: 分段序号，从0开始
+//	Start: 分段在输入文件中的开始时间
+//	End: 分段在输入文件中的结束时间
+//	Duration: 分段的实际时长，由ffprobe获取，无法获取时使用segment muxer分段列表中的时长
+//	Size: 分段文件大小，单位为字节
+type Segment struct {
+	Path     string        // 分段文件路径
+	Index    int           // 分段序号
+	Start    time.Duration // 开始时间
+	End      time.Duration // 结束时间
+	Duration time.Duration // 实际时长
+	Size     int64         // 文件大小 (字节)
+}
+
+// Frame 提取的单帧图片及其信息
+// 信息来自showinfo过滤器的输出，PTS为该帧在输入文件中的时间
+type Frame struct {
+	Path       string        // 图片文件路径
+	PTS        time.Duration // 显示时间
+	FrameType  string        // 帧类型，如I、P、B
+	Width      int           // 宽度 (像素)
+	Height     int           // 高度 (像素)
+	SceneScore float64       // 场景切换分数，仅KeyFrameModeScene提取的帧有值
 }
 
 // ExtractKeyFramesParams 提取关键帧参数结构体
@@ -82,12 +236,492 @@ type SplitVideoParams struct {
 // 字段:
 //
 //	InputPath: 输入视频文件路径
+//	Input: 输入数据流，通过stdin传给ffmpeg，不能与InputPath同时设置；不支持KeyFrameModeCount
 //	OutputDir: 输出目录，用于存放提取的关键帧图片
-//	FrameInterval: 关键帧间隔，单位为秒
+//	Mode: 提取方式，为空时使用KeyFrameModeInterval
+//	FrameInterval: KeyFrameModeInterval的采样间隔，单位为秒
+//	SceneThreshold: KeyFrameModeScene的场景切换阈值，范围0-1，值越小越敏感，0表示使用默认值0.4
+//	Count: KeyFrameModeCount提取的帧数
 //	OutputPrefix: 输出文件名前缀
+//	Format: 图片格式，为空时使用ImageFormatJPEG
+//	Quality: JPEG/WebP图片质量，范围1-100，值越大质量越高，0表示使用编码器默认值；PNG为无损格式，不支持设置
+//	MaxWidth: 最大宽度，超出时按比例缩小，0表示不限制
+//	MaxHeight: 最大高度，超出时按比例缩小，0表示不限制
+//	Crop: 裁剪区域，在缩放之前应用，nil表示不裁剪
+//	TimeRange: 时间范围，只处理输入文件的一部分
 type ExtractKeyFramesParams struct {
-	InputPath     string // 输入视频文件路径
-	OutputDir     string // 输出目录
-	FrameInterval int    // 关键帧间隔 (秒)
-	OutputPrefix  string // 输出文件名前缀
+	InputPath      string       // 输入视频文件路径
+	Input          io.Reader    // 输入数据流
+	OutputDir      string       // 输出目录
+	Mode           KeyFrameMode // 提取方式
+	FrameInterval  int          // 采样间隔 (秒)
+	SceneThreshold float64      // 场景切换阈值
+	Count          int          // 提取帧数
+	OutputPrefix   string       // 输出文件名前缀
+	Format         ImageFormat  // 图片格式
+	Quality        int          // 图片质量 (1-100)
+	MaxWidth       int          // 最大宽度
+	MaxHeight      int          // 最大高度
+	Crop           *CropRect    // 裁剪区域
+	TimeRange                   // 时间范围
+}
+
+// StreamFramesParams 流式提取帧参数结构体
+// 选帧方式与ExtractKeyFramesParams相同，帧以原始像素的形式通过回调返回，不写入磁盘
+// 字段:
+//
+//	InputPath: 输入视频文件路径
+//	Input: 输入数据流，通过stdin传给ffmpeg，不能与InputPath同时设置；不支持KeyFrameModeCount
+//	Mode: 提取方式，为空时使用KeyFrameModeInterval
+//	FrameInterval: KeyFrameModeInterval的采样间隔，单位为秒
+//	SceneThreshold: KeyFrameModeScene的场景切换阈值，范围0-1，0表示使用默认值0.4
+//	Count: KeyFrameModeCount提取的帧数
+//	MaxWidth: 最大宽度，超出时按比例缩小，0表示不限制
+//	MaxHeight: 最大高度，超出时按比例缩小，0表示不限制
+//	Crop: 裁剪区域，在缩放之前应用，nil表示不裁剪
+//	TimeRange: 时间范围，只处理输入文件的一部分
+type StreamFramesParams struct {
+	InputPath      string       // 输入视频文件路径
+	Input          io.Reader    // 输入数据流
+	Mode           KeyFrameMode // 提取方式
+	FrameInterval  int          // 采样间隔 (秒)
+	SceneThreshold float64      // 场景切换阈值
+	Count          int          // 提取帧数
+	MaxWidth       int          // 最大宽度
+	MaxHeight      int          // 最大高度
+	Crop           *CropRect    // 裁剪区域
+	TimeRange                   // 时间范围
+}
+
+// FrameImage 流式提取的一帧
+// Frame中的Path为空；Image的Pix按RGBA顺序存放原始像素，每行Stride字节
+type FrameImage struct {
+	Frame
+	Image *image.RGBA // 帧画面
+}
+
+// FrameHandler 流式提取帧的回调函数，按时间顺序逐帧调用
+// FrameImage归回调函数所有，可以在返回后继续使用；返回错误时停止提取
+type FrameHandler func(frame *FrameImage) error
+
+// SpriteSheetParams 缩略图精灵图参数结构体
+// 用于按间隔采样缩略图，拼接为网格图片并生成WebVTT缩略图轨道，供播放器显示拖动预览
+// 字段:
+//
+//	InputPath: 输入视频文件路径
+//	Input: 输入数据流，通过stdin传给ffmpeg，不能与InputPath同时设置
+//	OutputDir: 输出目录，用于存放精灵图和WebVTT文件
+//	OutputPrefix: 输出文件名前缀，精灵图为"前缀001.jpg"，WebVTT文件为"前缀thumbnails.vtt"
+//	FrameInterval: 采样间隔，单位为秒
+//	Columns: 每张精灵图的列数，0表示使用默认值5
+//	Rows: 每张精灵图的行数，0表示使用默认值5；缩略图超过Columns*Rows时输出多张精灵图
+//	Width: 缩略图宽度，高度按比例计算，0表示使用默认值160；不会超过原始宽度
+//	Format: 图片格式，为空时使用ImageFormatJPEG
+//	Quality: JPEG/WebP图片质量，范围1-100，0表示使用编码器默认值
+//	BaseURL: WebVTT中精灵图地址的前缀，如"https://cdn.example.com/sprites/"，为空时只使用文件名
+//	TimeRange: 时间范围，只处理输入文件的一部分
+type SpriteSheetParams struct {
+	InputPath     string      // 输入视频文件路径
+	Input         io.Reader   // 输入数据流
+	OutputDir     string      // 输出目录
+	OutputPrefix  string      // 输出文件名前缀
+	FrameInterval int         // 采样间隔 (秒)
+	Columns       int         // 列数
+	Rows          int         // 行数
+	Width         int         // 缩略图宽度
+	Format        ImageFormat // 图片格式
+	Quality       int         // 图片质量 (1-100)
+	BaseURL       string      // WebVTT中的图片地址前缀
+	TimeRange                 // 时间范围
+}
+
+// SpriteSheet 精灵图生成结果
+type SpriteSheet struct {
+	Sheets     []string          // 精灵图文件路径，按顺序排列
+	VTTPath    string            // WebVTT文件路径
+	Thumbnails []SpriteThumbnail // 每张缩略图在精灵图中的位置，按时间排序
+}
+
+// SpriteThumbnail 一张缩略图对应的时间范围及其在精灵图中的区域
+type SpriteThumbnail struct {
+	Sheet  string        // 所在精灵图的文件路径
+	Start  time.Duration // 开始时间
+	End    time.Duration // 结束时间
+	X      int           // 区域左上角横坐标
+	Y      int           // 区域左上角纵坐标
+	Width  int           // 区域宽度
+	Height int           // 区域高度
+}
+
+// PreviewAnimationParams 预览动画参数结构体
+// 用于从视频中截取片段生成GIF或动画WebP预览
+// 设置了Start、End或Duration时截取该范围；否则自动挑选Highlights个场景变化最明显的片段拼接在一起
+// 字段:
+//
+//	InputPath: 输入视频文件路径
+//	Input: 输入数据流，通过stdin传给ffmpeg，不能与InputPath同时设置；必须设置时间范围，不支持自动挑选片段
+//	OutputPath: 输出文件路径
+//	Format: 动画格式，为空时根据OutputPath的扩展名推断，无法识别时使用AnimationFormatGIF
+//	Width: 输出宽度，高度按比例计算，0表示使用默认值320；不会超过原始宽度
+//	FPS: 输出帧率，0表示使用默认值10
+//	Loop: 播放次数，0表示无限循环
+//	Quality: WebP图片质量，范围1-100，0表示使用编码器默认值；GIF不支持设置
+//	Highlights: 自动挑选的片段数，0表示使用默认值3；设置了时间范围时不能设置
+//	HighlightLength: 自动挑选的每个片段的时长，0表示使用默认值2秒；设置了时间范围时不能设置
+//	TimeRange: 时间范围，只处理输入文件的一部分
+type PreviewAnimationParams struct {
+	InputPath       string          // 输入视频文件路径
+	Input           io.Reader       // 输入数据流
+	OutputPath      string          // 输出文件路径
+	Format          AnimationFormat // 动画格式
+	Width           int             // 输出宽度
+	FPS             float64         // 输出帧率
+	Loop            int             // 播放次数
+	Quality         int             // WebP图片质量 (1-100)
+	Highlights      int             // 自动挑选的片段数
+	HighlightLength time.Duration   // 自动挑选的片段时长
+	TimeRange                       // 时间范围
+}
+
+// AnimationFormat 定义预览动画的格式
+type AnimationFormat string
+
+const (
+	// AnimationFormatGIF 通过palettegen/paletteuse生成调色板，画质优于默认的256色
+	AnimationFormatGIF AnimationFormat = "gif"
+	// AnimationFormatWebP 动画WebP，需要ffmpeg包含libwebp
+	AnimationFormatWebP AnimationFormat = "webp"
+)
+
+// ImageFormat 定义输出图片的格式
+type ImageFormat string
+
+const (
+	ImageFormatJPEG ImageFormat = "jpeg" // JPEG，文件扩展名为.jpg
+	ImageFormatPNG  ImageFormat = "png"  // PNG，无损
+	ImageFormatWebP ImageFormat = "webp" // WebP，需要ffmpeg包含libwebp
+)
+
+// CropRect 裁剪区域，坐标和尺寸均基于原始视频画面，单位为像素
+type CropRect struct {
+	X      int // 左上角横坐标
+	Y      int // 左上角纵坐标
+	Width  int // 宽度
+	Height int // 高度
+}
+
+// KeyFrameMode 定义提取关键帧的方式
+type KeyFrameMode string
+
+const (
+	// KeyFrameModeInterval 每隔FrameInterval秒采样一帧
+	KeyFrameModeInterval KeyFrameMode = "interval"
+	// KeyFrameModeIFrames 提取所有I帧，通过-skip_frame nokey只解码关键帧，速度最快
+	KeyFrameModeIFrames KeyFrameMode = "iframes"
+	// KeyFrameModeScene 提取场景切换处的帧
+	KeyFrameModeScene KeyFrameMode = "scene"
+	// KeyFrameModeCount 在整个时长内均匀提取Count帧
+	KeyFrameModeCount KeyFrameMode = "count"
+)
+
+// StreamType 定义媒体流类型
+type StreamType string
+
+const (
+	StreamTypeVideo      StreamType = "video"      // 视频流
+	StreamTypeAudio      StreamType = "audio"      // 音频流
+	StreamTypeSubtitle   StreamType = "subtitle"   // 字幕流
+	StreamTypeData       StreamType = "data"       // 数据流
+	StreamTypeAttachment StreamType = "attachment" // 附件（如字体）
+)
+
+// MediaInfo 媒体文件信息结构体
+// 由Probe方法通过ffprobe获取
+// 字段:
+//
+//	Format: 容器格式信息
+//	Streams: 所有媒体流，按流索引排列
+//	Chapters: 章节列表
+type MediaInfo struct {
+	Format   FormatInfo   // 容器格式信息
+	Streams  []StreamInfo // 所有媒体流
+	Chapters []Chapter    // 章节列表
+}
+
+// FormatInfo 容器格式信息结构体
+// 字段:
+//
+//	Filename: 文件路径
+//	FormatName: 容器格式名称，如"mov,mp4,m4a,3gp,3g2,mj2"
+//	FormatLongName: 容器格式描述
+//	StartTime: 起始时间
+//	Duration: 总时长
+//	Size: 文件大小，单位为字节
+//	BitRate: 总码率，单位为bit/s
+//	StreamCount: 媒体流数量
+//	Tags: 容器级元数据标签
+type FormatInfo struct {
+	Filename       string            // 文件路径
+	FormatName     string            // 容器格式名称
+	FormatLongName string            // 容器格式描述
+	StartTime      time.Duration     // 起始时间
+	Duration       time.Duration     // 总时长
+	Size           int64             // 文件大小 (字节)
+	BitRate        int64             // 总码率 (bit/s)
+	StreamCount    int               // 媒体流数量
+	Tags           map[string]string // 元数据标签
+}
+
+// StreamInfo 媒体流信息结构体
+// 视频相关字段只对视频流有效，音频相关字段只对音频流有效
+// 字段:
+//
+//	Index: 流索引
+//	Type: 流类型
+//	CodecName: 编解码器名称，如"h264"、"aac"
+//	CodecLongName: 编解码器描述
+//	Profile: 编码配置，如"High"、"LC"
+//	BitRate: 码率，单位为bit/s
+//	StartTime: 起始时间
+//	Duration: 时长
+//	Width: 视频宽度
+//	Height: 视频高度
+//	PixelFormat: 像素格式，如"yuv420p"
+//	FrameRate: 平均帧率
+//	SampleRate: 音频采样率，单位为Hz
+//	Channels: 音频声道数
+//	ChannelLayout: 声道布局，如"stereo"
+//	Language: 语言代码，如"eng"、"chi"
+//	Title: 流标题
+//	Disposition: 流的处置标志
+//	Tags: 流级元数据标签
+type StreamInfo struct {
+	Index         int               // 流索引
+	Type          StreamType        // 流类型
+	CodecName     string            // 编解码器名称
+	CodecLongName string            // 编解码器描述
+	Profile       string            // 编码配置
+	BitRate       int64             // 码率 (bit/s)
+	StartTime     time.Duration     // 起始时间
+	Duration      time.Duration     // 时长
+	Width         int               // 视频宽度
+	Height        int               // 视频高度
+	PixelFormat   string            // 像素格式
+	FrameRate     float64           // 平均帧率
+	SampleRate    int               // 音频采样率 (Hz)
+	Channels      int               // 音频声道数
+	ChannelLayout string            // 声道布局
+	Language      string            // 语言代码
+	Title         string            // 流标题
+	Disposition   Disposition       // 处置标志
+	Tags          map[string]string // 元数据标签
+}
+
+// Disposition 媒体流处置标志结构体
+// 对应ffprobe输出中的disposition字段
+type Disposition struct {
+	Default         bool // 默认流
+	Dub             bool // 配音
+	Original        bool // 原声
+	Comment         bool // 评论音轨
+	Lyrics          bool // 歌词
+	Karaoke         bool // 卡拉OK
+	Forced          bool // 强制字幕
+	HearingImpaired bool // 听障辅助
+	VisualImpaired  bool // 视障辅助
+	AttachedPic     bool // 封面图片
+}
+
+// Chapter 章节信息结构体
+// 字段:
+//
+//	ID: 章节ID
+//	Start: 章节开始时间
+//	End: 章节结束时间
+//	Title: 章节标题
+//	Tags: 章节元数据标签
+type Chapter struct {
+	ID    int64             // 章节ID
+	Start time.Duration     // 开始时间
+	End   time.Duration     // 结束时间
+	Title string            // 章节标题
+	Tags  map[string]string // 元数据标签
+}
+
+// Container 定义输出容器格式
+type Container string
+
+const (
+	ContainerMP4    Container = "mp4"  // MPEG-4
+	ContainerMOV    Container = "mov"  // QuickTime
+	ContainerMKV    Container = "mkv"  // Matroska
+	ContainerWebM   Container = "webm" // WebM
+	ContainerMPEGTS Container = "ts"   // MPEG传输流
+	ContainerM4A    Container = "m4a"  // MPEG-4音频
+	ContainerAAC    Container = "aac"  // ADTS封装的AAC裸流
+	ContainerMKA    Container = "mka"  // Matroska音频
+	ContainerMP3    Container = "mp3"  // MP3音频
+	ContainerOGG    Container = "ogg"  // Ogg音频
+	ContainerOGA    Container = "oga"  // Ogg音频，.oga扩展名
+	ContainerOpus   Container = "opus" // Ogg Opus音频
+	ContainerFLAC   Container = "flac" // FLAC音频
+	ContainerWAV    Container = "wav"  // WAV音频
+)
+
+// VideoCodec 定义视频编码格式
+type VideoCodec string
+
+const (
+	VideoCodecH264 VideoCodec = "h264" // H.264/AVC，使用libx264编码
+	VideoCodecHEVC VideoCodec = "hevc" // H.265/HEVC，使用libx265编码
+	VideoCodecVP9  VideoCodec = "vp9"  // VP9，使用libvpx-vp9编码
+	VideoCodecAV1  VideoCodec = "av1"  // AV1，使用libaom-av1编码
+	VideoCodecCopy VideoCodec = "copy" // 不重新编码，直接复制视频流
+)
+
+// FragmentMode 定义MP4的分片方式
+// 分片MP4把索引拆分到每个分片中，写入时不需要回到文件开头，可以输出到管道
+type FragmentMode string
+
+const (
+	// FragmentModeFMP4 在每个关键帧处开始新分片，文件开头写入不含样本的moov
+	FragmentModeFMP4 FragmentMode = "fmp4"
+	// FragmentModeCMAF 符合CMAF规范的分片MP4，可直接用于DASH/HLS，仅支持mp4容器
+	FragmentModeCMAF FragmentMode = "cmaf"
+)
+
+// AudioCodec 定义音频编码格式
+type AudioCodec string
+
+const (
+	AudioCodecAAC  AudioCodec = "aac"  // AAC
+	AudioCodecOpus AudioCodec = "opus" // Opus，使用libopus编码
+	AudioCodecMP3  AudioCodec = "mp3"  // MP3，使用libmp3lame编码
+	AudioCodecFLAC AudioCodec = "flac" // FLAC无损
+	AudioCodecPCM  AudioCodec = "pcm"  // 16位PCM，用于WAV
+	AudioCodecCopy AudioCodec = "copy" // 不重新编码，直接复制音频流
+)
+
+// TranscodeParams 转码参数结构体
+// 用于配置Transcode方法的输出格式和编码参数，所有参数在启动ffmpeg之前校验
+// 字段:
+//
+//	InputPath: 输入文件路径
+//	OutputPath: 输出文件路径
+//	Input: 输入数据流，通过stdin传给ffmpeg，不能与InputPath同时设置
+//	Output: 输出数据流，ffmpeg通过stdout写入，不能与OutputPath同时设置；必须指定Container，且容器不能需要定位（如未分片的mp4、mov）
+//	Container: 输出容器格式，为空则根据输出文件扩展名推断
+//	Fragment: 输出分片MP4，仅支持mp4、mov、m4a容器，分片后可以写入Output或边生成边上传
+//	VideoCodec: 视频编码格式，为空则使用容器的默认编码，VideoCodecCopy表示直接复制
+//	CRF: 视频恒定质量因子，h264/hevc范围0-51，vp9/av1范围0-63，0表示使用编码器默认值；无损编码使用Lossless
+//	Lossless: 无损视频编码，仅支持h264、hevc、vp9，不能与CRF、VideoBitrate同时设置
+//	VideoBitrate: 视频目标码率，单位为kbit/s，不能与CRF同时设置
+//	Preset: 编码速度预设，如"ultrafast"、"medium"、"veryslow"
+//	Width: 输出宽度，0表示保持原始宽度；只设置宽高之一时按比例缩放
+//	Height: 输出高度，0表示保持原始高度
+//	FrameRate: 输出帧率，0表示保持原始帧率
+//	PixelFormat: 输出像素格式，如"yuv420p"
+//	DisableVideo: 不输出视频流
+//	AudioCodec: 音频编码格式，为空则使用容器的默认编码，AudioCodecCopy表示直接复制
+//	AudioBitrate: 音频码率，单位为kbit/s
+//	AudioSampleRate: 音频采样率，单位为Hz，0表示保持原始采样率
+//	AudioChannels: 音频声道数，0表示保持原始声道数
+//	DisableAudio: 不输出音频流
+//	TimeRange: 时间范围，只处理输入文件的一部分
+type TranscodeParams struct {
+	InputPath       string       // 输入文件路径
+	OutputPath      string       // 输出文件路径
+	Input           io.Reader    // 输入数据流
+	Output          io.Writer    // 输出数据流
+	Container       Container    // 输出容器格式
+	Fragment        FragmentMode // 分片方式
+	VideoCodec      VideoCodec   // 视频编码格式
+	CRF             int          // 恒定质量因子
+	Lossless        bool         // 无损视频编码
+	VideoBitrate    int          // 视频码率 (kbit/s)
+	Preset          string       // 编码速度预设
+	Width           int          // 输出宽度
+	Height          int          // 输出高度
+	FrameRate       float64      // 输出帧率
+	PixelFormat     string       // 像素格式
+	DisableVideo    bool         // 不输出视频流
+	AudioCodec      AudioCodec   // 音频编码格式
+	AudioBitrate    int          // 音频码率 (kbit/s)
+	AudioSampleRate int          // 音频采样率 (Hz)
+	AudioChannels   int          // 音频声道数
+	DisableAudio    bool         // 不输出音频流
+	TimeRange                    // 时间范围
+}
+
+// HLSSegmentType 定义HLS分段的容器格式
+type HLSSegmentType string
+
+const (
+	HLSSegmentTS   HLSSegmentType = "ts"   // MPEG-TS分段，兼容性最好
+	HLSSegmentFMP4 HLSSegmentType = "fmp4" // 分片MP4分段，每个码率档位有一个初始化分段init_<档位名称>.mp4
+)
+
+// HLSRendition HLS码率阶梯中的一个档位
+// 字段:
+//
+//	Name: 档位名称，用作子目录名，只能包含字母、数字、下划线和连字符；为空时使用"<Height>p"，如"720p"
+//	Width: 输出宽度，0表示按Height等比例缩放
+//	Height: 输出高度，0表示按Width等比例缩放；宽高都为0时保持原始尺寸，此时必须设置Name
+//	VideoBitrate: 视频目标码率，单位为kbit/s
+//	AudioBitrate: 音频码率，单位为kbit/s，0表示使用默认值128
+type HLSRendition struct {
+	Name         string // 档位名称
+	Width        int    // 输出宽度
+	Height       int    // 输出高度
+	VideoBitrate int    // 视频码率 (kbit/s)
+	AudioBitrate int    // 音频码率 (kbit/s)
+}
+
+// HLSParams HLS打包参数结构体
+// 用于把输入视频编码为多个码率档位并打包为HLS，所有档位在同一次ffmpeg运行中编码，
+// 关键帧和分段边界在各档位之间对齐，播放器可以在任意分段处切换码率
+// 输出目录下生成主播放列表master.m3u8，每个档位的媒体播放列表和分段位于以档位名称命名的子目录中
+// 字段:
+//
+//	InputPath: 输入视频文件路径，需要先通过ffprobe判断是否有音频流，因此不支持数据流输入
+//	OutputDir: 输出目录
+//	Renditions: 码率阶梯，至少一个档位，按主播放列表中的顺序排列
+//	SegmentTime: 分段时长，单位为秒，0表示使用默认值6
+//	SegmentType: 分段格式，为空时使用HLSSegmentTS
+//	Preset: 编码速度预设，如"ultrafast"、"medium"、"veryslow"
+//	TimeRange: 时间范围，只处理输入文件的一部分
+type HLSParams struct {
+	InputPath   string         // 输入视频文件路径
+	OutputDir   string         // 输出目录
+	Renditions  []HLSRendition // 码率阶梯
+	SegmentTime int            // 分段时长 (秒)
+	SegmentType HLSSegmentType // 分段格式
+	Preset      string         // 编码速度预设
+	TimeRange                  // 时间范围
+}
+
+// HLSManifest HLS打包结果，描述本次写入的所有文件
+type HLSManifest struct {
+	MasterPlaylist string       // 主播放列表路径
+	Variants       []HLSVariant // 每个码率档位的输出，顺序与Renditions一致
+	Files          []string     // 写入的所有文件路径，依次为主播放列表以及每个档位的媒体播放列表、初始化分段和分段
+}
+
+// HLSVariant 一个码率档位的输出
+type HLSVariant struct {
+	Name        string       // 档位名称
+	Playlist    string       // 媒体播放列表路径
+	InitSegment string       // fMP4初始化分段路径，MPEG-TS分段时为空
+	Segments    []HLSSegment // 分段，按时间排序
+	Bandwidth   int          // 主播放列表中声明的峰值码率 (bit/s)
+	Width       int          // 输出宽度
+	Height      int          // 输出高度
+}
+
+// HLSSegment HLS媒体播放列表中的一个分段
+type HLSSegment struct {
+	Path     string        // 分段文件路径
+	Start    time.Duration // 在输入文件中的开始时间
+	Duration time.Duration // 分段时长
+	Size     int64         // 文件大小 (字节)
 }
@@ -0,0 +1,45 @@
+#!/bin/sh
+# 压缩ffmpeg/ffprobe并生成对应的.sha256摘要文件，放入internal/ffmpeg/bin/<os>_<arch>目录供go:embed嵌入
+# 摘要针对解压后的二进制文件，格式与sha256sum输出兼容
+#
+# 用法: scripts/package-binaries.sh <os>_<arch> <ffmpeg路径> <ffprobe路径>
+# 示例: scripts/package-binaries.sh linux_amd64 ./ffmpeg ./ffprobe
+set -eu
+
+if [ $# -ne 3 ]; then
+	echo "usage: $0 <os>_<arch> <ffmpeg> <ffprobe>" >&2
+	exit 2
+fi
+
+platform=$1
+dir=$(cd "$(dirname "$0")/.." && pwd)/internal/ffmpeg/bin/$platform
+if [ ! -d "$dir" ]; then
+	echo "unsupported platform $platform: $dir does not exist" >&2
+	exit 1
+fi
+
+# windows平台的文件名带.exe后缀
+ext=""
+case $platform in
+windows_*) ext=".exe" ;;
+esac
+
+sha256() {
+	if command -v sha256sum >/dev/null 2>&1; then
+		sha256sum "$1" | cut -d' ' -f1
+	else
+		shasum -a 256 "$1" | cut -d' ' -f1
+	fi
+}
+
+package() {
+	name=$1$ext
+	src=$2
+	gzip -9 -n -c "$src" > "$dir/$name.gz.tmp"
+	mv "$dir/$name.gz.tmp" "$dir/$name.gz"
+	printf '%s  %s\n' "$(sha256 "$src")" "$name" > "$dir/$name.gz.sha256"
+	echo "packaged $dir/$name.gz"
+}
+
+package ffmpeg "$2"
+package ffprobe "$3"